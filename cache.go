@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheEntry is the content stored in the cache for a particular feed.
+type CacheEntry struct {
+	// The datetime at which the feed was downloaded.
+	FetchDate time.Time `json:"fetched"`
+	// The offers found in the feed, stored in the same format used by the
+	// JSON feeds of JobFluent whatever the provider of the feed is.
+	Offers []Offer `json:"feed"`
+	// The validators of the response the feed was downloaded from, if any.
+	Validators *Validators `json:"validators,omitempty"`
+}
+
+// cacheFile is the layout of the files in the cache. The feed used to be
+// stored as returned by the server, in the format of the location, and
+// feeds that were not in JSON format were stored as a JSON string. Offers
+// are now always stored as a JSON feed, but older files are still read.
+type cacheFile struct {
+	FetchDate  time.Time       `json:"fetched"`
+	Format     string          `json:"format,omitempty"`
+	Feed       json.RawMessage `json:"feed"`
+	Validators *Validators     `json:"validators,omitempty"`
+}
+
+// offers decodes the offers stored in the file.
+func (file *cacheFile) offers() ([]Offer, error) {
+	content := []byte(file.Feed)
+	if file.Format != "" && file.Format != FeedFormatJSON {
+		var text string
+		if err := json.Unmarshal(file.Feed, &text); err != nil {
+			return nil, err
+		}
+		content = []byte(text)
+	}
+	decoder, err := DecoderForFormat(file.Format)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.Decode(content)
+}
+
+// FeedSource tells where the offers presented to the user come from.
+type FeedSource struct {
+	// Whether the offers were loaded from the cache instead of the network.
+	Cached bool
+	// Whether the server told that the feed did not change since it was
+	// cached, so the cached offers were used.
+	Unchanged bool
+	// The datetime at which the feed was downloaded.
+	FetchDate time.Time
+}
+
+// String describes the source in a way that can be shown to the user. Feeds
+// that were just downloaded are not worth describing.
+func (source *FeedSource) String() string {
+	if source != nil && source.Unchanged {
+		return "Feed unchanged"
+	}
+	if source == nil || !source.Cached {
+		return ""
+	}
+	return fmt.Sprintf("Cached data, %s old", formatAge(time.Since(source.FetchDate)))
+}
+
+// formatAge presents a duration using the most significant unit only.
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "less than a minute"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+}
+
+// cachePath returns the path to the file that caches the feed with the
+// given slug. Files are stored in the user cache directory, which follows
+// the XDG specification on UNIX systems.
+func cachePath(slug string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("Cannot locate cache directory: %s", err)
+	}
+	return filepath.Join(dir, "jobflucli", slug+".json"), nil
+}
+
+// ReadCache loads the cached copy of the feed with the given slug.
+func ReadCache(slug string) (*CacheEntry, error) {
+	path, err := cachePath(slug)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read cached feed: %s", err)
+	}
+	var file cacheFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("Cannot parse cached feed: %s", err)
+	}
+	offers, err := file.offers()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot parse cached feed: %s", err)
+	}
+	return &CacheEntry{FetchDate: file.FetchDate, Offers: offers, Validators: file.Validators}, nil
+}
+
+// WriteCache stores a copy of the offers found in the feed with the given
+// slug, so that they can be used later when the network is not available.
+// The validators of the response are stored too, if given, so that the
+// feed is only downloaded again if it changed.
+func WriteCache(slug string, offers []Offer, validators *Validators) error {
+	path, err := cachePath(slug)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("Cannot create cache directory: %s", err)
+	}
+	content, err := json.Marshal(CacheEntry{FetchDate: time.Now(), Offers: offers, Validators: validators})
+	if err != nil {
+		return fmt.Errorf("Cannot encode cached feed: %s", err)
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("Cannot write cached feed: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	gocontext "context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// command is a non-interactive subcommand, used for scripting the
+// application without presenting the terminal user interface.
+type command struct {
+	// The name used in the command line to invoke the command.
+	name string
+	// Short description of the arguments accepted by the command.
+	usage string
+	// Short description of what the command does.
+	description string
+	// Runs the command given the arguments that follow its name.
+	run func(args []string, offline bool) error
+}
+
+// commands is the list of subcommands understood by the application.
+var commands = []command{
+	{"list", "--location <slug> [--tag <tag>...] [--all] [--search <text>] [--preset <name>] [--filter <expression>]", "print the offers in a location", runList},
+	{"show", "[--location <slug>] <id>", "print the details of an offer", runShow},
+	{"tags", "--location <slug>", "print the tags used in a location", runTags},
+	{"export", "--location <slug> [--id <id>] [filters] [--format <format>] [--output <file>]", "export offers as json, ndjson, csv or markdown", runExport},
+	{"watch", "--location <slug> [filters] [--interval <duration>] [--exec <command>]", "poll the feeds and run a command for new offers", runWatch},
+}
+
+// usage prints the help message of the application.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: jobflucli [--offline] [--config <file>] [command [arguments]]\n\n")
+	fmt.Fprintf(out, "Without a command, the terminal user interface is started.\n\n")
+	fmt.Fprintf(out, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %s %s\n    \t%s\n", cmd.name, cmd.usage, cmd.description)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// runCommand executes the subcommand with the given name.
+func runCommand(name string, args []string, offline bool) error {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(args, offline)
+		}
+	}
+	return fmt.Errorf("Unknown command %q, try --help", name)
+}
+
+// stringList is a flag value that can be given multiple times.
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// newFlagSet prepares the flags shared by every subcommand.
+func newFlagSet(name string, offline *bool) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.BoolVar(offline, "offline", *offline, "use the cached feeds instead of the network")
+	return flags
+}
+
+// parseLocations converts a comma separated list of slugs or titles into
+// the locations they refer to.
+func parseLocations(slugs string) ([]Location, error) {
+	if slugs == "" {
+		return nil, fmt.Errorf("A location is required, use --location")
+	}
+	var locations []Location
+	for _, slug := range strings.Split(slugs, ",") {
+		location, ok := LocationBySlug(strings.TrimSpace(slug))
+		if !ok {
+			return nil, fmt.Errorf("Unknown location %q", slug)
+		}
+		locations = append(locations, location)
+	}
+	return locations, nil
+}
+
+// loadContext builds a context with the offers for the locations in the
+// given comma separated list of slugs or titles.
+func loadContext(slugs string, offline bool) (*Context, error) {
+	locations, err := parseLocations(slugs)
+	if err != nil {
+		return nil, err
+	}
+	context := &Context{offline: offline}
+	if err := context.SetOffersByLocations(gocontext.Background(), locations); err != nil {
+		return nil, err
+	}
+	return context, nil
+}
+
+// filterFlags holds the flags used to filter the offers of a location.
+type filterFlags struct {
+	location *string
+	tags     stringList
+	matchAll *bool
+	search   *string
+	preset   *string
+	query    *string
+}
+
+// addFilterFlags registers in the flag set the flags used to pick a location
+// and filter its offers.
+func addFilterFlags(flags *flag.FlagSet) *filterFlags {
+	filters := new(filterFlags)
+	filters.location = flags.String("location", "", "comma separated locations to retrieve offers from")
+	flags.Var(&filters.tags, "tag", "only use offers with this tag, can be repeated")
+	filters.matchAll = flags.Bool("all", false, "require every tag instead of any of them")
+	filters.search = flags.String("search", "", "only use offers containing this text")
+	filters.preset = flags.String("preset", "", "only use offers passing the preset with this `name` from the config file")
+	filters.query = flags.String("filter", "", "only use offers passing this `expression`, such as 'tag:golang AND posted:<7d'")
+	return filters
+}
+
+// locations returns the comma separated list of locations given in the
+// flags. If no location is given, the locations of the preset are used.
+func (filters *filterFlags) locations() (string, error) {
+	preset, err := filters.findPreset()
+	if err != nil {
+		return "", err
+	}
+	if *filters.location == "" && preset != nil {
+		return strings.Join(preset.Locations, ","), nil
+	}
+	return *filters.location, nil
+}
+
+// findPreset returns the preset given in the flags, or nil if no preset
+// was given.
+func (filters *filterFlags) findPreset() (*FilterPreset, error) {
+	if *filters.preset == "" {
+		return nil, nil
+	}
+	preset := config.FindPreset(*filters.preset)
+	if preset == nil {
+		return nil, fmt.Errorf("Unknown preset %q", *filters.preset)
+	}
+	return preset, nil
+}
+
+// load builds a context with the offers for the location given in the
+// flags, and applies the filters given in the flags.
+func (filters *filterFlags) load(offline bool) (*Context, error) {
+	locations, err := filters.locations()
+	if err != nil {
+		return nil, err
+	}
+	context, err := loadContext(locations, offline)
+	if err != nil {
+		return nil, err
+	}
+	if err := filters.apply(context); err != nil {
+		return nil, err
+	}
+	return context, nil
+}
+
+// apply sets in the context the filters given in the flags.
+func (filters *filterFlags) apply(context *Context) error {
+	preset, err := filters.findPreset()
+	if err != nil {
+		return err
+	}
+	context.SetPreset(preset)
+	if len(filters.tags) > 0 {
+		context.SetTagFilter(&TagFilter{Tags: filters.tags, MatchAll: *filters.matchAll})
+	}
+	context.SetSearchQuery(*filters.search)
+	if *filters.query != "" {
+		query, err := ParseQuery(*filters.query)
+		if err != nil {
+			return err
+		}
+		context.SetQuery(query)
+	}
+	return nil
+}
+
+// runList prints a line for each offer in a location that passes the filters.
+func runList(args []string, offline bool) error {
+	flags := newFlagSet("list", &offline)
+	filters := addFilterFlags(flags)
+	flags.Parse(args)
+
+	context, err := filters.load(offline)
+	if err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, offer := range context.FilteredOffers() {
+		fmt.Fprintf(out, "%d\t%s\t%s\t%s\n",
+			offer.ID,
+			offer.CreationDate.Format("2006-01-02"),
+			strings.TrimSpace(offer.Company),
+			strings.TrimSpace(offer.Position))
+	}
+	return out.Flush()
+}
+
+// runShow prints the details of an offer. If no location is given, every
+// location is looked into until the offer is found.
+func runShow(args []string, offline bool) error {
+	flags := newFlagSet("show", &offline)
+	location := flags.String("location", "", "location the offer belongs to")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("An offer ID is required")
+	}
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("Invalid offer ID %q", flags.Arg(0))
+	}
+
+	slugs := []string{*location}
+	if *location == "" {
+		slugs = locationSlugs()
+	}
+	for _, slug := range slugs {
+		context, err := loadContext(slug, offline)
+		if err != nil {
+			return err
+		}
+		if offer := context.GetOffer(id); offer != nil {
+			printOffer(offer)
+			return nil
+		}
+	}
+	return fmt.Errorf("Offer %d not found", id)
+}
+
+// printOffer writes the details of an offer to the standard output.
+func printOffer(offer *Offer) {
+	fmt.Printf("Position: %s\n", strings.TrimSpace(offer.Position))
+	fmt.Printf("Company:  %s\n", strings.TrimSpace(offer.Company))
+	fmt.Printf("Date:     %s\n", offer.CreationDate.Format("Mon, 2 Jan 2006 15:04:05"))
+	fmt.Printf("Tags:     %s\n", strings.Join(offer.Tags, ", "))
+	fmt.Printf("URL:      %s\n", offer.URL)
+	fmt.Printf("\n%s\n", cleanContent(offer.Description))
+}
+
+// runTags prints the tags used in a location along with their offer count.
+func runTags(args []string, offline bool) error {
+	flags := newFlagSet("tags", &offline)
+	location := flags.String("location", "", "comma separated locations to list tags from")
+	flags.Parse(args)
+
+	context, err := loadContext(*location, offline)
+	if err != nil {
+		return err
+	}
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, tag := range context.Tags() {
+		fmt.Fprintf(out, "%d\t%s\n", tag.Count, tag.Tag)
+	}
+	return out.Flush()
+}
+
+// runExport writes the offers in a location that pass the filters, or a
+// single offer if an ID is given, to the standard output or to a file.
+func runExport(args []string, offline bool) error {
+	flags := newFlagSet("export", &offline)
+	filters := addFilterFlags(flags)
+	id := flags.Int("id", 0, "only export the offer with this ID")
+	format := flags.String("format", "", "json, ndjson, csv or markdown (default guessed from --output, or json)")
+	output := flags.String("output", "", "file to write to (default stdout)")
+	flags.Parse(args)
+
+	context, err := filters.load(offline)
+	if err != nil {
+		return err
+	}
+	offers := context.FilteredOffers()
+	if *id != 0 {
+		offer := context.GetOffer(*id)
+		if offer == nil {
+			return fmt.Errorf("Offer %d not found", *id)
+		}
+		offers = []Offer{*offer}
+	}
+
+	if *format == "" {
+		*format = FormatFromPath(*output)
+	}
+	if *output == "" {
+		return ExportOffers(os.Stdout, *format, offers)
+	}
+	return ExportFile(*output, *format, offers)
+}
+
+// locationSlugs returns the slug of every location, in the order they were
+// declared.
+func locationSlugs() []string {
+	locations := SortedLocations()
+	slugs := make([]string, len(locations))
+	for i, location := range locations {
+		slugs[i] = Locations[location].Slug
+	}
+	return slugs
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config holds the settings that can be customised through the config file.
+// Every setting that is not present in the file keeps its default value.
+//
+// An example config file looks like this:
+//
+//	server = "https://www.jobfluent.com"
+//	feed_path = "/{language}/feeds/jobs-{slug}.{format}"
+//	language = "en"
+//	refresh_interval = "15m"
+//
+//	[[locations]]
+//	title = "Lisbon"
+//	slug = "lisboa"
+//	format = "xml"
+//
+//	[http]
+//	timeout = "30s"
+//	retries = 2
+//
+//	[[providers]]
+//	name = "Other board"
+//
+//	[[providers.feeds]]
+//	title = "Go jobs"
+//	slug = "other-golang"
+//	url = "https://jobs.example.com/golang.rss"
+//
+//	[[presets]]
+//	name = "remote-go"
+//	locations = ["remoto"]
+//	tags = ["golang"]
+//	exclude_tags = ["php"]
+type Config struct {
+	// The base URL of the HTTP server to fetch offers from.
+	Server string `toml:"server"`
+	// The path to the feed of a location. The {language}, {slug} and
+	// {format} placeholders are replaced by the language, the location slug
+	// and the format of the feed.
+	FeedPath string `toml:"feed_path"`
+	// The language segment used in the feed path.
+	Language string `toml:"language"`
+	// The locations that have available feeds. If the list is not empty,
+	// it replaces the built-in list of locations.
+	Locations []LocationConfig `toml:"locations"`
+	// How often the offers are fetched again in background, such as "15m".
+	// Zero disables refreshing.
+	RefreshInterval Duration `toml:"refresh_interval"`
+	// The shell command run by the watch command for every new offer.
+	WatchCommand string `toml:"watch_command"`
+	// The shell command used to open offers in a browser. The %s placeholder
+	// is replaced by the URL. When empty, $BROWSER or the system opener is
+	// used. On Windows, the command is run without a shell.
+	Browser string `toml:"browser"`
+	// Named sets of criteria to filter offers.
+	Presets []FilterPreset `toml:"presets"`
+	// The settings used to send HTTP requests.
+	HTTP HTTPConfig `toml:"http"`
+	// Other job boards to retrieve offers from, which publish RSS or Atom
+	// feeds.
+	Providers []ProviderConfig `toml:"providers"`
+}
+
+// Duration is a time.Duration that can be read from the config file using
+// the same format as time.ParseDuration.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses the duration from the config file.
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+// LocationConfig describes a location in the config file.
+type LocationConfig struct {
+	Title string `toml:"title"`
+	Slug  string `toml:"slug"`
+	// The format of the feed, either "json" or "xml". JSON is used if empty.
+	Format string `toml:"format"`
+}
+
+// ProviderConfig describes in the config file a job board that publishes
+// RSS or Atom feeds.
+type ProviderConfig struct {
+	Name  string       `toml:"name"`
+	Feeds []FeedConfig `toml:"feeds"`
+}
+
+// FeedConfig describes a feed of a provider in the config file. The slug
+// is used to refer to the feed from the command line, and must not be used
+// by any other location.
+type FeedConfig struct {
+	Title string `toml:"title"`
+	Slug  string `toml:"slug"`
+	URL   string `toml:"url"`
+}
+
+// config holds the settings in use by the application.
+var config = defaultConfig()
+
+// httpClient sends the HTTP requests of the application, using the HTTP
+// settings in config. It is shared so that connections are reused. The
+// default settings are always valid.
+var httpClient, _ = NewHTTPClient(config.HTTP)
+
+// defaultConfig returns the settings used when there is no config file.
+func defaultConfig() Config {
+	return Config{
+		Server:   TargetServer,
+		FeedPath: "/{language}/feeds/jobs-{slug}.{format}",
+		Language: "es",
+		HTTP:     defaultHTTPConfig(),
+	}
+}
+
+// DefaultConfigPath returns the path to the config file that is loaded when
+// no other file is given. It is stored in the user config directory, which
+// follows the XDG specification on UNIX systems.
+func DefaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("Cannot locate config directory: %s", err)
+	}
+	return filepath.Join(dir, "jobflucli", "config.toml"), nil
+}
+
+// LoadConfig reads the config file at the given path and puts its settings
+// in use. If no path is given, the default config file is read if present.
+func LoadConfig(path string) error {
+	if path == "" {
+		defaultPath, err := DefaultConfigPath()
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(defaultPath); os.IsNotExist(err) {
+			// Not having a config file is fine.
+			return nil
+		}
+		path = defaultPath
+	}
+
+	loaded := defaultConfig()
+	meta, err := toml.DecodeFile(path, &loaded)
+	if err != nil {
+		return fmt.Errorf("Cannot read config file: %s", err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("Unknown setting %q in config file %s", undecoded[0].String(), path)
+	}
+	return applyConfig(loaded)
+}
+
+// applyConfig validates the given settings and puts them in use.
+func applyConfig(loaded Config) error {
+	if loaded.Server == "" || loaded.FeedPath == "" {
+		return fmt.Errorf("The server and the feed path cannot be empty")
+	}
+	if loaded.HTTP.Timeout.Duration < 0 || loaded.HTTP.Retries < 0 || loaded.HTTP.RetryDelay.Duration < 0 {
+		return fmt.Errorf("The HTTP timeout, retries and retry delay cannot be negative")
+	}
+	if loaded.HTTP.MaxResponseSize <= 0 {
+		return fmt.Errorf("The maximum HTTP response size must be positive")
+	}
+	client, err := NewHTTPClient(loaded.HTTP)
+	if err != nil {
+		return err
+	}
+	providers, err := configProviders(loaded)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for i, preset := range loaded.Presets {
+		if preset.Name == "" {
+			return fmt.Errorf("Preset %d in config file has no name", i+1)
+		}
+		if names[preset.Name] {
+			return fmt.Errorf("Preset %q is defined twice in config file", preset.Name)
+		}
+		names[preset.Name] = true
+	}
+	config = loaded
+	httpClient = client
+	SetProviders(providers)
+	return nil
+}
+
+// configProviders builds the providers described by the given settings.
+// When locations are given, they replace the built-in JobFluent locations.
+func configProviders(loaded Config) ([]Provider, error) {
+	jobFluent := jobFluentLocations
+	if len(loaded.Locations) > 0 {
+		jobFluent = make([]LocationData, len(loaded.Locations))
+		for i, location := range loaded.Locations {
+			if location.Slug == "" {
+				return nil, fmt.Errorf("Location %d in config file has no slug", i+1)
+			}
+			if location.Title == "" {
+				location.Title = location.Slug
+			}
+			if location.Format == "" {
+				location.Format = FeedFormatJSON
+			}
+			if _, err := DecoderForFormat(location.Format); err != nil {
+				return nil, fmt.Errorf("Location %s in config file: %s", location.Slug, err)
+			}
+			jobFluent[i] = LocationData{title: location.Title, Slug: location.Slug, Format: location.Format}
+		}
+	}
+	providers := []Provider{NewJobFluentProvider(jobFluent)}
+
+	for i, provider := range loaded.Providers {
+		if provider.Name == "" {
+			return nil, fmt.Errorf("Provider %d in config file has no name", i+1)
+		}
+		feeds := make([]LocationData, len(provider.Feeds))
+		for j, feed := range provider.Feeds {
+			if feed.Slug == "" || feed.URL == "" {
+				return nil, fmt.Errorf("Feed %d of provider %s in config file needs a slug and an URL", j+1, provider.Name)
+			}
+			if feed.Title == "" {
+				feed.Title = feed.Slug
+			}
+			feeds[j] = LocationData{title: feed.Title, Slug: feed.Slug, Format: FeedFormatRSS, URL: feed.URL}
+		}
+		providers = append(providers, NewFeedProvider(provider.Name, feeds))
+	}
+
+	// Slugs identify cached feeds and stored state, so they must be unique.
+	slugs := make(map[string]bool)
+	for _, provider := range providers {
+		for _, location := range provider.Locations() {
+			if slugs[location.Slug] {
+				return nil, fmt.Errorf("Slug %q is used by several locations in config file", location.Slug)
+			}
+			slugs[location.Slug] = true
+		}
+	}
+	return providers, nil
+}
+
+// FindPreset returns the preset with the given name, or nil if there is no
+// such preset.
+func (c *Config) FindPreset(name string) *FilterPreset {
+	for i := range c.Presets {
+		if c.Presets[i].Name == name {
+			return &c.Presets[i]
+		}
+	}
+	return nil
+}
+
+// FeedURL builds the URL of the feed for the location with the given slug,
+// published in the given format.
+func (c *Config) FeedURL(slug, format string) string {
+	if format == "" {
+		format = FeedFormatJSON
+	}
+	path := strings.NewReplacer("{language}", c.Language, "{slug}", slug, "{format}", format).Replace(c.FeedPath)
+	return strings.TrimSuffix(c.Server, "/") + path
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jobflucli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Restore the built-in settings once the test is done.
+	defaultProviders, defaultClient := Providers, httpClient
+	defer func() {
+		SetProviders(defaultProviders)
+		config = defaultConfig()
+		httpClient = defaultClient
+	}()
+
+	path := filepath.Join(dir, "config.toml")
+	content := `
+server = "http://localhost:8080/"
+language = "en"
+refresh_interval = "15m"
+
+[[locations]]
+title = "Lisbon"
+slug = "lisboa"
+
+[[locations]]
+slug = "porto"
+format = "xml"
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfig(path); err != nil {
+		t.Fatalf("LoadConfig() failed: %s", err)
+	}
+
+	if url := config.FeedURL("lisboa", FeedFormatJSON); url != "http://localhost:8080/en/feeds/jobs-lisboa.json" {
+		t.Errorf("FeedURL() returned unexpected URL %s", url)
+	}
+	if config.RefreshInterval.Duration != 15*time.Minute {
+		t.Errorf("Refresh interval was not parsed: %s", config.RefreshInterval)
+	}
+	if len(Locations) != 2 {
+		t.Fatalf("Expected the config file to replace the locations, got %d", len(Locations))
+	}
+	if Locations[0].title != "Lisbon" || Locations[1].title != "porto" {
+		t.Errorf("Locations were not loaded in order: %v", Locations)
+	}
+	if Locations[0].Format != FeedFormatJSON || Locations[1].Format != FeedFormatXML {
+		t.Errorf("Feed formats were not loaded: %v", Locations)
+	}
+}
+
+func TestLoadConfigUnknownSetting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jobflucli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(path, []byte(`sever = "typo"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfig(path); err == nil {
+		t.Errorf("Expected LoadConfig() to reject unknown settings")
+	}
+}
+
+func TestLoadConfigProviders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jobflucli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defaultProviders, defaultClient := Providers, httpClient
+	defer func() {
+		SetProviders(defaultProviders)
+		config = defaultConfig()
+		httpClient = defaultClient
+	}()
+
+	path := filepath.Join(dir, "config.toml")
+	content := `
+[[providers]]
+name = "Other board"
+
+[[providers.feeds]]
+title = "Go jobs"
+slug = "other-golang"
+url = "http://localhost:8080/golang.rss"
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfig(path); err != nil {
+		t.Fatalf("LoadConfig() failed: %s", err)
+	}
+
+	if len(Providers) != 2 || Providers[0].Name() != "JobFluent" || Providers[1].Name() != "Other board" {
+		t.Fatalf("Providers were not loaded: %v", Providers)
+	}
+	if len(Locations) != len(jobFluentLocations)+1 {
+		t.Fatalf("Expected the feed to be added to the built-in locations, got %d", len(Locations))
+	}
+	location, ok := LocationBySlug("other-golang")
+	if !ok {
+		t.Fatalf("The feed was not added to the locations")
+	}
+	if data := Locations[location]; data.URL != "http://localhost:8080/golang.rss" || data.provider != Providers[1] {
+		t.Errorf("The feed was not loaded properly: %v", data)
+	}
+
+	// Slugs must be unique.
+	duplicated := content + `
+[[providers.feeds]]
+slug = "berlin"
+url = "http://localhost:8080/berlin.rss"
+`
+	if err := ioutil.WriteFile(path, []byte(duplicated), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfig(path); err == nil {
+		t.Errorf("Expected LoadConfig() to reject duplicated slugs")
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	gocontext "context"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -22,12 +25,47 @@ type Offer struct {
 	ID int `json:"id"`
 }
 
+// TagCount pairs a tag with the number of offers labelled with it.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
 // Context holds the application state.
 type Context struct {
-	location Location
-	offers   []Offer
-	idIndex  map[int]Offer
-	tagIndex map[string][]int
+	locations []Location
+	offers    []Offer
+	source    *FeedSource // where the offers were retrieved from
+	offline   bool        // whether to use the cache instead of the network
+	state     *State      // information kept across sessions
+	idIndex   map[int]Offer
+	tagIndex  map[string][]int
+
+	// The locations each offer was retrieved from.
+	locationIndex map[int][]Location
+
+	// The IDs of the offers that are new since the previous fetch.
+	newOffers map[int]bool
+
+	// The text that the search looks into for each offer, and the cleaned
+	// description that it includes.
+	searchIndex      map[int]string
+	descriptionIndex map[int]string
+	searchQuery      string
+
+	// The filter expression in use.
+	query *Query
+
+	// The filter preset in use, and the function compiled from it.
+	preset     *FilterPreset
+	presetFunc func(offer Offer) bool
+
+	// Whether the offers hidden by the user are matched anyway.
+	showHidden bool
+
+	// The tag filter in use, and the IDs of the offers that pass it.
+	tagFilter  *TagFilter
+	tagMatches map[int]bool
 }
 
 // updateIndices destroys and re-creates the id index and the tag index
@@ -36,12 +74,17 @@ type Context struct {
 func (context *Context) updateIndices() {
 	context.idIndex = make(map[int]Offer)
 	context.tagIndex = make(map[string][]int)
+	context.searchIndex = make(map[int]string)
+	context.descriptionIndex = make(map[int]string)
 
 	// presenceIndex tries to make faster guessing which tags are in tagIndex.
 	presenceIndex := make(map[string]bool)
 	for _, offer := range context.offers {
 		// Put the offer in the ID index.
 		context.idIndex[offer.ID] = offer
+		description := searchDescription(offer)
+		context.descriptionIndex[offer.ID] = description
+		context.searchIndex[offer.ID] = searchText(offer, description)
 
 		// Extract tags and put the offer in the tag index.
 		for _, tag := range offer.Tags {
@@ -61,21 +104,149 @@ func (context *Context) updateIndices() {
 func (context *Context) SetOffers(offers []Offer) {
 	context.offers = offers
 	context.updateIndices()
+	context.SetTagFilter(context.tagFilter)
 }
 
 // SetOffersByLocation retrieves the offers in the given location, and
 // places them in the current context. It also will update the offers
-// index used by the application.
-func (context *Context) SetOffersByLocation(location Location) error {
-	offers, err := FetchOffers(location)
+// index used by the application. If the context is offline, or if the
+// network fails, the offers are retrieved from the cache.
+func (context *Context) SetOffersByLocation(ctx gocontext.Context, location Location) error {
+	return context.SetOffersByLocations(ctx, []Location{location})
+}
+
+// SetOffersByLocations works like SetOffersByLocation, but retrieves the
+// offers of several locations at once and merges them.
+func (context *Context) SetOffersByLocations(ctx gocontext.Context, locations []Location) error {
+	results, err := LoadLocations(ctx, locations, context.offline)
 	if err != nil {
 		return err
 	}
-	context.location = location
+	return context.SetLocationOffers(results)
+}
+
+// SetLocationOffers places in the context the offers that were retrieved
+// for one or more locations. The filters are reset since they may not apply
+// to the new list of offers. See UpdateLocationOffers.
+func (context *Context) SetLocationOffers(results []LocationOffers) error {
+	context.tagFilter = nil
+	context.searchQuery = ""
+	context.query = nil
+	return context.UpdateLocationOffers(results)
+}
+
+// UpdateLocationOffers places in the context the offers that were retrieved
+// for one or more locations, keeping the filters in use. Offers published in
+// several locations are only kept once. The offers that are new since the
+// previous fetch are tracked and stored, and an error is returned if they
+// cannot be stored.
+func (context *Context) UpdateLocationOffers(results []LocationOffers) error {
+	context.locations = make([]Location, 0, len(results))
+	context.locationIndex = make(map[int][]Location)
+	context.source = nil
+
+	var offers []Offer
+	for _, result := range results {
+		context.locations = append(context.locations, result.Location)
+		for _, offer := range result.Offers {
+			if _, seen := context.locationIndex[offer.ID]; !seen {
+				offers = append(offers, offer)
+			}
+			context.locationIndex[offer.ID] = append(context.locationIndex[offer.ID], result.Location)
+		}
+
+		// If any location was cached, tell about the oldest cache. Feeds
+		// are only told to be unchanged if every one of them is.
+		switch {
+		case context.source == nil, !context.source.Cached && result.Source.Cached:
+			context.source = result.Source
+		case context.source.Cached && result.Source.Cached && result.Source.FetchDate.Before(context.source.FetchDate):
+			context.source = result.Source
+		case context.source.Unchanged && !result.Source.Unchanged && !result.Source.Cached:
+			context.source = result.Source
+		}
+	}
+
 	context.SetOffers(offers)
+	return context.trackNewOffers(results)
+}
+
+// trackNewOffers compares the offers that were just fetched with the offers
+// found in the previous fetch of each location. Offers loaded from the cache
+// are not a new fetch, even if the feed was unchanged, so the result of the
+// latest fetch is used instead.
+// The first fetch of a location is taken as a baseline, and no offer is new.
+func (context *Context) trackNewOffers(results []LocationOffers) error {
+	state := context.persistentState()
+	context.newOffers = make(map[int]bool)
+	changed := false
+
+	for _, result := range results {
+		slug := Locations[result.Location].Slug
+		if result.Source == nil || !result.Source.Cached && !result.Source.Unchanged {
+			previous, known := state.Seen[slug]
+			seen := make(map[int]bool)
+			for _, id := range previous {
+				seen[id] = true
+			}
+
+			ids := make([]int, 0, len(result.Offers))
+			var fresh []int
+			for _, offer := range result.Offers {
+				ids = append(ids, offer.ID)
+				if known && !seen[offer.ID] {
+					fresh = append(fresh, offer.ID)
+				}
+			}
+			state.Seen[slug] = ids
+			state.New[slug] = fresh
+			changed = true
+		}
+
+		for _, id := range state.New[slug] {
+			context.newOffers[id] = true
+		}
+	}
+
+	if changed {
+		return state.Save()
+	}
 	return nil
 }
 
+// IsNew returns whether the offer with the given ID was not present in the
+// previous fetch of its location.
+func (context *Context) IsNew(id int) bool {
+	return context.newOffers[id]
+}
+
+// NewOfferCount returns how many offers were new in the latest fetch of the
+// given location.
+func (context *Context) NewOfferCount(location Location) int {
+	return len(context.persistentState().New[Locations[location].Slug])
+}
+
+// LocationNames returns the titles of the locations the offers in the
+// context were retrieved from.
+func (context *Context) LocationNames() []string {
+	return locationTitles(context.locations)
+}
+
+// OfferLocationNames returns the titles of the locations the offer with the
+// given ID was published in.
+func (context *Context) OfferLocationNames(id int) []string {
+	return locationTitles(context.locationIndex[id])
+}
+
+// locationTitles converts a list of locations into their titles.
+func locationTitles(locations []Location) []string {
+	titles := make([]string, len(locations))
+	for i, location := range locations {
+		titles[i] = Locations[location].title
+	}
+	return titles
+}
+
 func (c *Context) GetOffer(id int) *Offer {
 	offer, ok := c.idIndex[id]
 	if !ok {
@@ -87,3 +258,237 @@ func (c *Context) GetOffer(id int) *Offer {
 func (c *Context) CountOffers() int {
 	return len(c.offers)
 }
+
+// Tags returns every tag found in the tag index, alphabetically sorted, along
+// with the number of offers labelled with each tag.
+func (context *Context) Tags() []TagCount {
+	tags := make([]TagCount, 0, len(context.tagIndex))
+	for tag, ids := range context.tagIndex {
+		tags = append(tags, TagCount{Tag: tag, Count: len(ids)})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
+
+// SetTagFilter restricts the offers matched by the context to those that
+// pass the given tag filter. The tag index is used to precompute the set of
+// matching offers. A nil filter removes the restriction.
+func (context *Context) SetTagFilter(filter *TagFilter) {
+	context.tagFilter = filter
+	context.tagMatches = nil
+	if filter == nil || len(filter.Tags) == 0 {
+		return
+	}
+
+	// Count how many of the filter tags each offer is labelled with.
+	hits := make(map[int]int)
+	for _, tag := range filter.Tags {
+		for _, id := range context.tagIndex[tag] {
+			hits[id]++
+		}
+	}
+
+	context.tagMatches = make(map[int]bool)
+	for id, count := range hits {
+		if !filter.MatchAll || count == len(filter.Tags) {
+			context.tagMatches[id] = true
+		}
+	}
+}
+
+// Matches returns whether the given offer passes every filter currently
+// active in the context. It can be used as the filter function of a list.
+func (context *Context) Matches(offer Offer) bool {
+	if !context.showHidden && context.IsHidden(offer) {
+		return false
+	}
+	if context.tagMatches != nil && !context.tagMatches[offer.ID] {
+		return false
+	}
+	if context.searchQuery != "" && !strings.Contains(context.searchIndex[offer.ID], context.searchQuery) {
+		return false
+	}
+	if context.presetFunc != nil && !context.presetFunc(offer) {
+		return false
+	}
+	if context.query != nil && !context.query.Matches(offer, context.offerText, context.offerDescription) {
+		return false
+	}
+	return true
+}
+
+// offerText returns the text that the search looks into for the offer.
+func (context *Context) offerText(offer Offer) string {
+	return context.searchIndex[offer.ID]
+}
+
+// offerDescription returns the cleaned description of the offer, as kept
+// in the search index.
+func (context *Context) offerDescription(offer Offer) string {
+	return context.descriptionIndex[offer.ID]
+}
+
+// SetQuery restricts the offers matched by the context to those that pass
+// the given filter expression. A nil query removes the restriction.
+func (context *Context) SetQuery(query *Query) {
+	context.query = query
+}
+
+// SetPreset restricts the offers matched by the context to those that pass
+// the criteria of the given preset. Unlike other filters, the preset is
+// kept when the offers of a different location are placed in the context.
+// A nil preset removes the restriction.
+func (context *Context) SetPreset(preset *FilterPreset) {
+	context.preset = preset
+	context.presetFunc = nil
+	if preset != nil {
+		context.presetFunc = preset.Compile(context.offerText)
+	}
+}
+
+// FilteredOffers returns the offers that pass every filter currently active
+// in the context, in the same order they were retrieved.
+func (context *Context) FilteredOffers() []Offer {
+	filtered := make([]Offer, 0, len(context.offers))
+	for _, offer := range context.offers {
+		if context.Matches(offer) {
+			filtered = append(filtered, offer)
+		}
+	}
+	return filtered
+}
+
+// SetSearchQuery restricts the offers matched by the context to those that
+// contain the given text in their position, company, tags or description.
+// The search is case insensitive. An empty query removes the restriction.
+func (context *Context) SetSearchQuery(query string) {
+	context.searchQuery = strings.ToLower(query)
+}
+
+// ClearFilters removes every filter currently active in the context.
+func (context *Context) ClearFilters() {
+	context.SetTagFilter(nil)
+	context.SetSearchQuery("")
+	context.SetPreset(nil)
+	context.SetQuery(nil)
+}
+
+// persistentState returns the state kept across sessions. If the context was
+// not given a state, an empty state that is not backed by a file is used.
+func (context *Context) persistentState() *State {
+	if context.state == nil {
+		context.state = new(State)
+		context.state.init()
+	}
+	return context.state
+}
+
+// IsHidden returns whether the offer was hidden by the user, either by
+// itself or because of its company.
+func (context *Context) IsHidden(offer Offer) bool {
+	return context.persistentState().Hidden.Hides(offer)
+}
+
+// SetHidden hides or shows the given offer, and stores the change so that
+// it is remembered by later sessions.
+func (context *Context) SetHidden(id int, hidden bool) error {
+	state := context.persistentState()
+	if hidden {
+		state.Hidden.Offers[id] = true
+	} else {
+		delete(state.Hidden.Offers, id)
+	}
+	return state.Save()
+}
+
+// HideCompany hides the offers of the companies described by the given
+// rule, as explained in HideList.AddCompany, and stores the change so that
+// it is remembered by later sessions.
+func (context *Context) HideCompany(rule string) error {
+	state := context.persistentState()
+	if err := state.Hidden.AddCompany(rule); err != nil {
+		return err
+	}
+	return state.Save()
+}
+
+// UnhideCompany removes every rule that hides the offers of the given
+// company, and returns how many rules were removed.
+func (context *Context) UnhideCompany(company string) (int, error) {
+	state := context.persistentState()
+	removed := state.Hidden.RemoveCompany(company)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, state.Save()
+}
+
+// SetShowHidden tells whether the offers hidden by the user are matched by
+// the context anyway, so that they can be reviewed.
+func (context *Context) SetShowHidden(show bool) {
+	context.showHidden = show
+}
+
+// IsRead returns whether the offer with the given ID was opened before.
+func (context *Context) IsRead(id int) bool {
+	return context.persistentState().Read[id]
+}
+
+// SetRead marks the offer with the given ID as read or unread, and stores
+// the change so that it is remembered by later sessions.
+func (context *Context) SetRead(id int, read bool) error {
+	state := context.persistentState()
+	if state.Read[id] == read {
+		return nil
+	}
+	if read {
+		state.Read[id] = true
+	} else {
+		delete(state.Read, id)
+	}
+	return state.Save()
+}
+
+// IsStarred returns whether the offer with the given ID was starred.
+func (context *Context) IsStarred(id int) bool {
+	_, ok := context.persistentState().Starred[id]
+	return ok
+}
+
+// SetStarred stars or unstars the given offer, and stores the change so that
+// it is remembered by later sessions. A copy of the offer is kept, so that
+// it is available even if the offer is removed from the feed.
+func (context *Context) SetStarred(offer Offer, starred bool) error {
+	state := context.persistentState()
+	if starred {
+		state.Starred[offer.ID] = offer
+	} else {
+		delete(state.Starred, offer.ID)
+	}
+	return state.Save()
+}
+
+// StarredOffers returns every starred offer, most recent first.
+func (context *Context) StarredOffers() []Offer {
+	starred := context.persistentState().Starred
+	offers := make([]Offer, 0, len(starred))
+	for _, offer := range starred {
+		offers = append(offers, offer)
+	}
+	sort.Slice(offers, func(i, j int) bool {
+		return offers[i].CreationDate.After(offers[j].CreationDate)
+	})
+	return offers
+}
+
+// GetStarredOffer returns the copy kept of a starred offer, or nil if the
+// offer with the given ID was not starred.
+func (context *Context) GetStarredOffer(id int) *Offer {
+	offer, ok := context.persistentState().Starred[id]
+	if !ok {
+		return nil
+	}
+	return &offer
+}
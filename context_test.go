@@ -114,3 +114,232 @@ func TestContextTagIndex(t *testing.T) {
 		}
 	}
 }
+
+func TestContextTagFilter(t *testing.T) {
+	cases := []struct {
+		filter *TagFilter
+		want   []int
+	}{
+		{nil, []int{1000, 2000, 3000}},
+		{&TagFilter{Tags: []string{"one"}}, []int{1000, 2000}},
+		{&TagFilter{Tags: []string{"alpha", "gamma"}}, []int{1000, 3000}},
+		{&TagFilter{Tags: []string{"one", "two"}, MatchAll: true}, []int{2000}},
+		{&TagFilter{Tags: []string{"alpha", "gamma"}, MatchAll: true}, []int{}},
+	}
+
+	context := new(Context)
+	context.SetOffers(offers)
+
+	for _, c := range cases {
+		context.SetTagFilter(c.filter)
+		matched := make([]int, 0)
+		for _, offer := range offers {
+			if context.Matches(offer) {
+				matched = append(matched, offer.ID)
+			}
+		}
+		if len(matched) != len(c.want) {
+			t.Errorf("Filter %v matched %v, expected %v", c.filter, matched, c.want)
+			continue
+		}
+		for i := range matched {
+			if matched[i] != c.want[i] {
+				t.Errorf("Filter %v matched %v, expected %v", c.filter, matched, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestContextTags(t *testing.T) {
+	context := new(Context)
+	context.SetOffers(offers)
+
+	tags := context.Tags()
+	if len(tags) != 6 {
+		t.Fatalf("Tags() returned %d tags, expected 6", len(tags))
+	}
+	if tags[0].Tag != "alpha" || tags[0].Count != 1 {
+		t.Errorf("Tags() is not sorted or counted properly: %v", tags[0])
+	}
+	for _, tag := range tags {
+		if tag.Tag == "two" && tag.Count != 2 {
+			t.Errorf("Tags() did not count offers for tag two: %d", tag.Count)
+		}
+	}
+}
+
+func TestContextSearchQuery(t *testing.T) {
+	cases := []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"actionrocks", 1},
+		{"number", 3},
+		{"SECURITY", 1},
+		{"gamma", 1},
+		{"missing", 0},
+	}
+
+	context := new(Context)
+	context.SetOffers(offers)
+
+	for _, c := range cases {
+		context.SetSearchQuery(c.query)
+		matched := 0
+		for _, offer := range offers {
+			if context.Matches(offer) {
+				matched++
+			}
+		}
+		if matched != c.want {
+			t.Errorf("Search %q matched %d offers, expected %d", c.query, matched, c.want)
+		}
+	}
+}
+
+func TestContextLocationOffers(t *testing.T) {
+	context := new(Context)
+	context.SetLocationOffers([]LocationOffers{
+		{LocationBerlin, offers[:2], &FeedSource{FetchDate: time.Now()}},
+		{LocationRemote, offers[1:], &FeedSource{FetchDate: time.Now()}},
+	})
+
+	if context.CountOffers() != 3 {
+		t.Errorf("Offers in several locations were not de-duplicated: %d", context.CountOffers())
+	}
+	if names := context.LocationNames(); len(names) != 2 || names[0] != "Berlin" || names[1] != "Remote" {
+		t.Errorf("LocationNames() did not return the merged locations: %v", names)
+	}
+
+	cases := []struct {
+		id   int
+		want int
+	}{
+		{1000, 1},
+		{2000, 2},
+		{3000, 1},
+	}
+	for _, c := range cases {
+		if names := context.OfferLocationNames(c.id); len(names) != c.want {
+			t.Errorf("Offer %d was published in %v, expected %d locations", c.id, names, c.want)
+		}
+	}
+}
+
+func TestContextReadState(t *testing.T) {
+	context := new(Context)
+	context.SetOffers(offers)
+
+	if context.IsRead(1000) {
+		t.Errorf("Offers should be unread by default")
+	}
+	context.SetRead(1000, true)
+	if !context.IsRead(1000) || context.IsRead(2000) {
+		t.Errorf("SetRead() did not mark only the given offer as read")
+	}
+	context.SetRead(1000, false)
+	if context.IsRead(1000) {
+		t.Errorf("SetRead() did not mark the offer as unread")
+	}
+}
+
+func TestContextNewOffers(t *testing.T) {
+	context := new(Context)
+	live := &FeedSource{FetchDate: time.Now()}
+
+	// The first fetch is only a baseline.
+	context.SetLocationOffers([]LocationOffers{{LocationBerlin, offers[:2], live}})
+	if context.IsNew(1000) || context.NewOfferCount(LocationBerlin) != 0 {
+		t.Errorf("Offers in the first fetch should not be new")
+	}
+
+	context.SetLocationOffers([]LocationOffers{{LocationBerlin, offers, live}})
+	if context.IsNew(1000) || !context.IsNew(3000) || context.NewOfferCount(LocationBerlin) != 1 {
+		t.Errorf("Expected only offer 3000 to be new")
+	}
+
+	// Cached feeds keep the result of the latest fetch.
+	cached := &FeedSource{Cached: true, FetchDate: time.Now()}
+	context.SetLocationOffers([]LocationOffers{{LocationBerlin, offers, cached}})
+	if !context.IsNew(3000) {
+		t.Errorf("Expected offer 3000 to still be new when using the cache")
+	}
+
+	context.SetLocationOffers([]LocationOffers{{LocationBerlin, offers, live}})
+	if context.IsNew(3000) || context.NewOfferCount(LocationBerlin) != 0 {
+		t.Errorf("Expected no new offers when the feed did not change")
+	}
+}
+
+func TestContextPreset(t *testing.T) {
+	cases := []struct {
+		preset FilterPreset
+		want   int
+	}{
+		{FilterPreset{Name: "all"}, 3},
+		{FilterPreset{Name: "tag", Tags: []string{"two"}}, 2},
+		{FilterPreset{Name: "and", Tags: []string{"one", "two"}, MatchAll: true}, 1},
+		{FilterPreset{Name: "exclude", Tags: []string{"two"}, ExcludeTags: []string{"beta"}}, 1},
+		{FilterPreset{Name: "company", ExcludeCompanies: []string{"actionsoft inc"}}, 2},
+		{FilterPreset{Name: "query", Query: "Rockstar"}, 1},
+	}
+
+	context := new(Context)
+	context.SetOffers(offers)
+
+	for _, c := range cases {
+		preset := c.preset
+		context.SetPreset(&preset)
+		if matched := len(context.FilteredOffers()); matched != c.want {
+			t.Errorf("Preset %s matched %d offers, expected %d", c.preset.Name, matched, c.want)
+		}
+	}
+}
+
+func TestContextHidden(t *testing.T) {
+	context := new(Context)
+	context.SetOffers(offers)
+
+	if err := context.SetHidden(1000, true); err != nil {
+		t.Fatalf("SetHidden() failed: %s", err)
+	}
+	if matched := len(context.FilteredOffers()); matched != 2 {
+		t.Errorf("Hiding an offer matched %d offers, expected 2", matched)
+	}
+	if err := context.HideCompany("actionrocks inc"); err != nil {
+		t.Fatalf("HideCompany() failed: %s", err)
+	}
+	if matched := len(context.FilteredOffers()); matched != 1 {
+		t.Errorf("Hiding a company matched %d offers, expected 1", matched)
+	}
+	if err := context.HideCompany("/^action(ware|foo)/"); err != nil {
+		t.Fatalf("HideCompany() failed: %s", err)
+	}
+	if matched := len(context.FilteredOffers()); matched != 0 {
+		t.Errorf("Hiding a pattern matched %d offers, expected 0", matched)
+	}
+	if err := context.HideCompany("/(/"); err == nil {
+		t.Errorf("HideCompany() should fail with an invalid pattern")
+	}
+
+	context.SetShowHidden(true)
+	if matched := len(context.FilteredOffers()); matched != 3 {
+		t.Errorf("Showing hidden offers matched %d offers, expected 3", matched)
+	}
+	context.SetShowHidden(false)
+
+	if removed, _ := context.UnhideCompany("ActionWare Inc"); removed != 1 {
+		t.Errorf("UnhideCompany() removed %d rules, expected 1", removed)
+	}
+	if removed, _ := context.UnhideCompany("ActionRocks Inc"); removed != 1 {
+		t.Errorf("UnhideCompany() removed %d rules, expected 1", removed)
+	}
+	if err := context.SetHidden(1000, false); err != nil {
+		t.Fatalf("SetHidden() failed: %s", err)
+	}
+	if matched := len(context.FilteredOffers()); matched != 3 {
+		t.Errorf("Showing every offer again matched %d offers, expected 3", matched)
+	}
+}
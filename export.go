@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The formats that offers can be exported to.
+const (
+	FormatJSON     = "json"
+	FormatNDJSON   = "ndjson"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+// FormatFromPath guesses the export format to use given the extension of
+// the file name. JSON is used if the extension is not recognised.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".csv":
+		return FormatCSV
+	case ".md", ".markdown":
+		return FormatMarkdown
+	default:
+		return FormatJSON
+	}
+}
+
+// ExportOffers writes the given offers to the writer using the given format.
+func ExportOffers(w io.Writer, format string, offers []Offer) error {
+	switch format {
+	case FormatJSON:
+		return exportJSON(w, offers)
+	case FormatNDJSON:
+		return exportNDJSON(w, offers)
+	case FormatCSV:
+		return exportCSV(w, offers)
+	case FormatMarkdown:
+		return exportMarkdown(w, offers)
+	default:
+		return fmt.Errorf("Unknown export format %q", format)
+	}
+}
+
+// ExportFile writes the given offers to the file at the given path, using
+// the given format.
+func ExportFile(path, format string, offers []Offer) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Cannot create export file: %s", err)
+	}
+	if err := ExportOffers(file, format, offers); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("Cannot write export file: %s", err)
+	}
+	return nil
+}
+
+// exportJSON writes the offers as a JSON array, using the same fields that
+// are found in the feeds.
+func exportJSON(w io.Writer, offers []Offer) error {
+	if offers == nil {
+		offers = []Offer{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(offers); err != nil {
+		return fmt.Errorf("Cannot export offers: %s", err)
+	}
+	return nil
+}
+
+// exportNDJSON writes each offer as a JSON object in its own line.
+func exportNDJSON(w io.Writer, offers []Offer) error {
+	encoder := json.NewEncoder(w)
+	for _, offer := range offers {
+		if err := encoder.Encode(offer); err != nil {
+			return fmt.Errorf("Cannot export offers: %s", err)
+		}
+	}
+	return nil
+}
+
+// exportCSV writes the offers as a table with a header row. The description
+// is converted to Markdown so that it is readable in a spreadsheet.
+func exportCSV(w io.Writer, offers []Offer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "date", "company", "position", "tags", "url", "description"})
+	for _, offer := range offers {
+		writer.Write([]string{
+			strconv.Itoa(offer.ID),
+			offer.CreationDate.Format("2006-01-02 15:04:05"),
+			strings.TrimSpace(offer.Company),
+			strings.TrimSpace(offer.Position),
+			strings.Join(offer.Tags, ", "),
+			offer.URL,
+			cleanContent(offer.Description),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("Cannot export offers: %s", err)
+	}
+	return nil
+}
+
+// markdownEscaper escapes the characters that Markdown would take as
+// formatting in the fields of an offer, such as a position like "C#/C++".
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"#", `\#`, "<", `\<`, ">", `\>`,
+)
+
+// exportMarkdown writes a section for each offer, suitable for sharing.
+func exportMarkdown(w io.Writer, offers []Offer) error {
+	for i, offer := range offers {
+		if i > 0 {
+			fmt.Fprintf(w, "\n---\n\n")
+		}
+		fmt.Fprintf(w, "## %s\n\n", markdownEscaper.Replace(strings.TrimSpace(offer.Position)))
+		fmt.Fprintf(w, "- **Company:** %s\n", markdownEscaper.Replace(strings.TrimSpace(offer.Company)))
+		fmt.Fprintf(w, "- **Date:** %s\n", offer.CreationDate.Format("Mon, 2 Jan 2006 15:04:05"))
+		fmt.Fprintf(w, "- **Tags:** %s\n", markdownEscaper.Replace(strings.Join(offer.Tags, ", ")))
+		fmt.Fprintf(w, "- **URL:** <%s>\n\n", offer.URL)
+		if _, err := fmt.Fprintf(w, "%s\n", cleanContent(offer.Description)); err != nil {
+			return fmt.Errorf("Cannot export offers: %s", err)
+		}
+	}
+	return nil
+}
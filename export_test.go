@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportOffers are offers whose fields need quoting or escaping.
+var exportOffers = []Offer{
+	Offer{
+		ID:           1000,
+		URL:          "http://localhost:8080/offer/1000",
+		Description:  "Plain description",
+		Company:      "Acme, Inc",
+		Position:     `Senior "C#" *Ninja*`,
+		CreationDate: time.Date(2019, 5, 1, 10, 30, 0, 0, time.UTC),
+		Tags:         []string{"c#", "dot_net"},
+	},
+	Offer{
+		ID:           2000,
+		URL:          "http://localhost:8080/offer/2000",
+		Description:  "Second line\nThird line",
+		Company:      "Beta [Labs]",
+		Position:     "Code Ops",
+		CreationDate: time.Date(2019, 5, 2, 9, 0, 0, 0, time.UTC),
+		Tags:         []string{"go"},
+	},
+}
+
+func TestFormatFromPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"offers.json", FormatJSON},
+		{"offers.ndjson", FormatNDJSON},
+		{"offers.jsonl", FormatNDJSON},
+		{"offers.csv", FormatCSV},
+		{"OFFERS.CSV", FormatCSV},
+		{"offers.md", FormatMarkdown},
+		{"offers.markdown", FormatMarkdown},
+		{"dir.csv/offers", FormatJSON},
+		{"offers.txt", FormatJSON},
+	}
+	for _, c := range cases {
+		if format := FormatFromPath(c.path); format != c.want {
+			t.Errorf("FormatFromPath(%q) returned %q, expected %q", c.path, format, c.want)
+		}
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	var out bytes.Buffer
+	if err := ExportOffers(&out, "xls", exportOffers); err == nil {
+		t.Errorf("Expected unknown formats to be rejected")
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	cases := []struct {
+		offers []Offer
+		want   int
+	}{
+		{exportOffers, 2},
+		{nil, 0},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		if err := ExportOffers(&out, FormatJSON, c.offers); err != nil {
+			t.Fatalf("ExportOffers() failed: %s", err)
+		}
+		var decoded []Offer
+		if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+			t.Fatalf("Exported JSON is not valid: %s", err)
+		}
+		if decoded == nil || len(decoded) != c.want {
+			t.Errorf("Expected an array of %d offers, got %s", c.want, out.String())
+		}
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := ExportOffers(&out, FormatNDJSON, exportOffers); err != nil {
+		t.Fatalf("ExportOffers() failed: %s", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(exportOffers) {
+		t.Fatalf("Expected one line per offer, got %q", out.String())
+	}
+	for i, line := range lines {
+		var offer Offer
+		if err := json.Unmarshal([]byte(line), &offer); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %s", i+1, err)
+		}
+		if offer.ID != exportOffers[i].ID || offer.Description != exportOffers[i].Description {
+			t.Errorf("Line %d does not hold offer %d: %s", i+1, exportOffers[i].ID, line)
+		}
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := ExportOffers(&out, FormatCSV, exportOffers); err != nil {
+		t.Fatalf("ExportOffers() failed: %s", err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("Exported CSV is not valid: %s", err)
+	}
+
+	cases := []struct {
+		row    int
+		column int
+		want   string
+	}{
+		{0, 0, "id"},
+		{0, 6, "description"},
+		{1, 0, "1000"},
+		{1, 1, "2019-05-01 10:30:00"},
+		{1, 2, "Acme, Inc"},
+		{1, 3, `Senior "C#" *Ninja*`},
+		{1, 4, "c#, dot_net"},
+		{2, 5, "http://localhost:8080/offer/2000"},
+	}
+	if len(records) != len(exportOffers)+1 {
+		t.Fatalf("Expected a header and %d rows, got %v", len(exportOffers), records)
+	}
+	for _, c := range cases {
+		if value := records[c.row][c.column]; value != c.want {
+			t.Errorf("Row %d, column %d is %q, expected %q", c.row, c.column, value, c.want)
+		}
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	var out bytes.Buffer
+	if err := ExportOffers(&out, FormatMarkdown, exportOffers); err != nil {
+		t.Fatalf("ExportOffers() failed: %s", err)
+	}
+	markdown := out.String()
+
+	cases := []string{
+		`## Senior "C\#" \*Ninja\*` + "\n",
+		`- **Company:** Acme, Inc` + "\n",
+		`- **Date:** Wed, 1 May 2019 10:30:00` + "\n",
+		`- **Tags:** c\#, dot\_net` + "\n",
+		`- **URL:** <http://localhost:8080/offer/1000>` + "\n",
+		`- **Company:** Beta \[Labs\]` + "\n",
+		"\n---\n\n## Code Ops\n",
+	}
+	for _, want := range cases {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected %q in exported Markdown:\n%s", want, markdown)
+		}
+	}
+	if count := strings.Count(markdown, "\n---\n"); count != len(exportOffers)-1 {
+		t.Errorf("Expected offers to be separated by rules, found %d", count)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The formats that feeds can be published in.
+const (
+	// The JSON feeds published by JobFluent.
+	FeedFormatJSON = "json"
+	// The XML feeds published by JobFluent, which use RSS.
+	FeedFormatXML = "xml"
+	// Any RSS or Atom feed, such as those published by other job boards.
+	FeedFormatRSS = "rss"
+)
+
+// FeedDecoder converts the raw content of a feed into offers.
+type FeedDecoder interface {
+	Decode(content []byte) ([]Offer, error)
+}
+
+// feedDecoders holds the decoder used for each feed format.
+var feedDecoders = map[string]FeedDecoder{
+	FeedFormatJSON: jsonFeedDecoder{},
+	FeedFormatXML:  xmlFeedDecoder{},
+	FeedFormatRSS:  syndicationFeedDecoder{},
+}
+
+// DecoderForFormat returns the decoder for feeds in the given format. An
+// empty format stands for JSON, which is the format used by default.
+func DecoderForFormat(format string) (FeedDecoder, error) {
+	if format == "" {
+		format = FeedFormatJSON
+	}
+	decoder, ok := feedDecoders[format]
+	if !ok {
+		return nil, fmt.Errorf("Unknown feed format %q", format)
+	}
+	return decoder, nil
+}
+
+// jsonFeedDecoder reads feeds that are a JSON array of offers.
+type jsonFeedDecoder struct{}
+
+func (jsonFeedDecoder) Decode(content []byte) ([]Offer, error) {
+	return unmarshalResponse(content)
+}
+
+// xmlFeedDecoder reads feeds published as RSS. The position is taken from
+// the title of each item and the tags from its categories. The company is
+// taken from the author of the item, or from a company element if there is
+// one, and the ID is taken from the guid or the link of the item.
+type xmlFeedDecoder struct{}
+
+// rssItem is an item of an RSS feed. Elements are matched by name in any
+// namespace, so that dc:creator or content:encoded are also understood.
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	ID          string   `xml:"id"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"encoded"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"date"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"creator"`
+	Company     string   `xml:"company"`
+	Categories  []string `xml:"category"`
+}
+
+// rssFeed is the document of an RSS feed.
+type rssFeed struct {
+	Items []rssItem `xml:"channel>item"`
+}
+
+// trailingNumber finds the number that ends the last segment of an URL or
+// a guid, which is used as the ID of the offer.
+var trailingNumber = regexp.MustCompile(`(\d+)\D*$`)
+
+func (xmlFeedDecoder) Decode(content []byte) ([]Offer, error) {
+	return decodeRSS(content, itemID)
+}
+
+// decodeRSS converts the items of an RSS document into offers, using the
+// given function to find the ID of each item. Items without a valid ID or
+// date are skipped, so that a single broken item does not hide the rest of
+// the feed. The feed is only rejected if none of its items is valid.
+func decodeRSS(content []byte, findID func(item rssItem) (int, error)) ([]Offer, error) {
+	var feed rssFeed
+	if err := xml.Unmarshal(content, &feed); err != nil {
+		return nil, fmt.Errorf("Cannot parse response offers: %s", err)
+	}
+
+	offers := make([]Offer, 0, len(feed.Items))
+	var skipped skippedItems
+	for i, item := range feed.Items {
+		id, err := findID(item)
+		if err != nil {
+			skipped.add(i, err)
+			continue
+		}
+		date, err := parseFeedDate(firstNonEmpty(item.PubDate, item.Date))
+		if err != nil {
+			skipped.add(i, err)
+			continue
+		}
+		offer := Offer{
+			ID:           id,
+			CreationDate: date,
+			Position:     strings.TrimSpace(item.Title),
+			Company:      strings.TrimSpace(firstNonEmpty(item.Company, item.Creator, item.Author)),
+			Tags:         make([]string, 0, len(item.Categories)),
+			Description:  firstNonEmpty(item.Content, item.Description),
+			URL:          strings.TrimSpace(item.Link),
+		}
+		for _, category := range item.Categories {
+			if category = strings.TrimSpace(category); category != "" {
+				offer.Tags = append(offer.Tags, category)
+			}
+		}
+		offers = append(offers, offer)
+	}
+	if len(offers) == 0 && skipped.count > 0 {
+		return nil, skipped.err("item")
+	}
+	return offers, nil
+}
+
+// skippedItems counts the items of a feed that could not be decoded,
+// keeping the first error found.
+type skippedItems struct {
+	count int
+	first error
+	index int
+}
+
+// add counts the item at the given index, which failed with the error.
+func (skipped *skippedItems) add(index int, err error) {
+	if skipped.count == 0 {
+		skipped.first, skipped.index = err, index
+	}
+	skipped.count++
+}
+
+// err describes the skipped items. The kind tells how the feed calls its
+// items, such as "item" or "entry".
+func (skipped *skippedItems) err(kind string) error {
+	return fmt.Errorf("Cannot parse response offers: %s %d: %s (%d invalid in total)",
+		kind, skipped.index+1, skipped.first, skipped.count)
+}
+
+// itemID finds the ID of the offer described by an RSS item. The ID is the
+// number that ends the last path segment of the guid or the link, so that
+// query strings such as ?page=2 are not taken for the ID.
+func itemID(item rssItem) (int, error) {
+	for _, candidate := range []string{item.ID, item.GUID, item.Link} {
+		if id, ok := segmentID(candidate); ok {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("no ID found")
+}
+
+// segmentID finds the number that ends the last path segment of the URL or
+// guid. Numbers from providerIDBase on are not valid, since they are used
+// by other job boards.
+func segmentID(text string) (int, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, false
+	}
+	if parsed, err := url.Parse(text); err == nil {
+		text = parsed.Path
+		if text == "" {
+			// Opaque URLs such as urn:jobs:12345 have no path.
+			text = parsed.Opaque
+		}
+	}
+	text = strings.TrimRight(text, "/")
+	segment := text[strings.LastIndexAny(text, "/:")+1:]
+	match := trailingNumber.FindStringSubmatch(segment)
+	if match == nil {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(match[1], 10, 32)
+	if err != nil || id >= providerIDBase {
+		return 0, false
+	}
+	return int(id), true
+}
+
+// parseFeedDate parses the publication date of an item, which is usually
+// given in RFC 1123 format by RSS feeds and in RFC 3339 format by Atom feeds.
+func parseFeedDate(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	layouts := []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		time.RFC3339,
+	}
+	for _, layout := range layouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", text)
+}
+
+// firstNonEmpty returns the first of the values that is not blank.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// syndicationFeedDecoder reads any RSS or Atom feed. Since the links of
+// other job boards do not always end with a number, the ID of each offer is
+// a hash of the guid or the link of the item.
+type syndicationFeedDecoder struct{}
+
+// atomEntry is an entry of an Atom feed.
+type atomEntry struct {
+	Title     string   `xml:"title"`
+	ID        string   `xml:"id"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Summary   atomText `xml:"summary"`
+	Content   atomText `xml:"content"`
+	Author    string   `xml:"author>name"`
+	Links     []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+}
+
+// atomText is a text construct of an Atom feed, such as the content of an
+// entry. Text and HTML are given as character data, while XHTML is given as
+// child elements.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// String returns the text, or the markup if it is given as XHTML.
+func (text atomText) String() string {
+	if text.Type == "xhtml" {
+		return strings.TrimSpace(text.Inner)
+	}
+	return text.Text
+}
+
+// atomFeed is the document of an Atom feed.
+type atomFeed struct {
+	Entries []atomEntry `xml:"entry"`
+}
+
+func (syndicationFeedDecoder) Decode(content []byte) ([]Offer, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(content, &root); err != nil {
+		return nil, fmt.Errorf("Cannot parse response offers: %s", err)
+	}
+	if root.XMLName.Local != "feed" {
+		return decodeRSS(content, func(item rssItem) (int, error) {
+			return hashID(firstNonEmpty(item.GUID, item.Link))
+		})
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(content, &feed); err != nil {
+		return nil, fmt.Errorf("Cannot parse response offers: %s", err)
+	}
+	// Invalid entries are skipped, as done for RSS items.
+	offers := make([]Offer, 0, len(feed.Entries))
+	var skipped skippedItems
+	for i, entry := range feed.Entries {
+		// The first link is used unless there is an alternate one.
+		link := ""
+		for _, candidate := range entry.Links {
+			if link == "" || candidate.Rel == "alternate" {
+				link = candidate.Href
+			}
+		}
+		id, err := hashID(firstNonEmpty(entry.ID, link))
+		if err != nil {
+			skipped.add(i, err)
+			continue
+		}
+		date, err := parseFeedDate(firstNonEmpty(entry.Published, entry.Updated))
+		if err != nil {
+			skipped.add(i, err)
+			continue
+		}
+		offer := Offer{
+			ID:           id,
+			CreationDate: date,
+			Position:     strings.TrimSpace(entry.Title),
+			Company:      strings.TrimSpace(entry.Author),
+			Tags:         make([]string, 0, len(entry.Categories)),
+			Description:  firstNonEmpty(entry.Content.String(), entry.Summary.String()),
+			URL:          strings.TrimSpace(link),
+		}
+		for _, category := range entry.Categories {
+			if term := strings.TrimSpace(category.Term); term != "" {
+				offer.Tags = append(offer.Tags, term)
+			}
+		}
+		offers = append(offers, offer)
+	}
+	if len(offers) == 0 && skipped.count > 0 {
+		return nil, skipped.err("entry")
+	}
+	return offers, nil
+}
+
+// providerIDBase is the lowest ID given to the offers of other job boards.
+// Offers are identified by their ID everywhere, such as in the read and
+// hidden lists, so the IDs taken from JobFluent feeds are kept below it to
+// make sure that both kinds never collide. Offers of other boards may still
+// collide among themselves if their hashes do, which is unlikely.
+const providerIDBase = 1 << 30
+
+// hashID builds the ID of an offer out of a text that identifies it. The ID
+// is always at least providerIDBase.
+func hashID(text string) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, fmt.Errorf("no ID found")
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(text))
+	return providerIDBase + int(hash.Sum32()%providerIDBase), nil
+}
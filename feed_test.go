@@ -0,0 +1,229 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// decodeFixture decodes the fixture in testdata using the decoder for the
+// given format.
+func decodeFixture(t *testing.T, name, format string) []Offer {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := DecoderForFormat(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offers, err := decoder.Decode(content)
+	if err != nil {
+		t.Fatalf("Cannot decode %s: %s", name, err)
+	}
+	return offers
+}
+
+func TestFeedDecodersAgree(t *testing.T) {
+	fromJSON := decodeFixture(t, "feed.json", FeedFormatJSON)
+	fromXML := decodeFixture(t, "feed.xml", FeedFormatXML)
+
+	if len(fromJSON) != 3 {
+		t.Fatalf("Expected 3 offers in the JSON feed, got %d", len(fromJSON))
+	}
+	if len(fromXML) != len(fromJSON) {
+		t.Fatalf("The XML feed has %d offers, the JSON feed has %d", len(fromXML), len(fromJSON))
+	}
+	for i := range fromJSON {
+		want, got := fromJSON[i], fromXML[i]
+		if !got.CreationDate.Equal(want.CreationDate) {
+			t.Errorf("Offer %d: date is %s, expected %s", want.ID, got.CreationDate, want.CreationDate)
+		}
+		// Dates were already compared, since their locations may differ.
+		got.CreationDate = want.CreationDate
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Offer %d: decoders disagree\njson: %+v\nxml:  %+v", want.ID, want, got)
+		}
+	}
+}
+
+func TestFeedDecoderErrors(t *testing.T) {
+	if _, err := DecoderForFormat("yaml"); err == nil {
+		t.Errorf("DecoderForFormat() should reject unknown formats")
+	}
+	if decoder, err := DecoderForFormat(""); err != nil || decoder != feedDecoders[FeedFormatJSON] {
+		t.Errorf("DecoderForFormat() should use JSON by default")
+	}
+
+	cases := []struct {
+		format  string
+		content string
+	}{
+		{FeedFormatJSON, `{"not": "an array"}`},
+		{FeedFormatXML, `<rss><channel><item>`},
+		{FeedFormatXML, `<rss><channel><item><title>No ID</title><pubDate>Wed, 01 May 2019 10:30:00 +0200</pubDate></item></channel></rss>`},
+		{FeedFormatXML, `<rss><channel><item><guid>1</guid><pubDate>yesterday</pubDate></item></channel></rss>`},
+	}
+	for _, c := range cases {
+		decoder, _ := DecoderForFormat(c.format)
+		if _, err := decoder.Decode([]byte(c.content)); err == nil {
+			t.Errorf("Decoding %q as %s should fail", c.content, c.format)
+		}
+	}
+}
+
+func TestItemID(t *testing.T) {
+	cases := []struct {
+		item rssItem
+		want int
+		ok   bool
+	}{
+		{rssItem{GUID: "https://www.jobfluent.com/jobs/go-developer-12345"}, 12345, true},
+		{rssItem{GUID: "12346"}, 12346, true},
+		{rssItem{Link: "https://www.jobfluent.com/jobs/12347/?page=2"}, 12347, true},
+		{rssItem{Link: "https://www.jobfluent.com/jobs/go-developer-12348.html#apply"}, 12348, true},
+		{rssItem{GUID: "urn:jobfluent:12349"}, 12349, true},
+		{rssItem{GUID: "99999999999999999999999", Link: "https://www.jobfluent.com/jobs/12350"}, 12350, true},
+		{rssItem{Link: "https://www.jobfluent.com/jobs/go-developer?page=2"}, 0, false},
+		{rssItem{GUID: "99999999999999999999999"}, 0, false},
+	}
+	for _, c := range cases {
+		id, err := itemID(c.item)
+		if c.ok && (err != nil || id != c.want) {
+			t.Errorf("itemID(%+v) returned %d, %v, expected %d", c.item, id, err, c.want)
+		} else if !c.ok && err == nil {
+			t.Errorf("itemID(%+v) returned %d, expected an error", c.item, id)
+		}
+	}
+}
+
+func TestHashIDRange(t *testing.T) {
+	texts := []string{
+		"https://jobs.example.com/go-developer",
+		"tag:jobs.example.com,2019:go-developer",
+		"12345",
+		"https://www.jobfluent.com/jobs/senior-go-developer-12345",
+	}
+	for _, text := range texts {
+		id, err := hashID(text)
+		if err != nil {
+			t.Fatalf("hashID(%q) failed: %s", text, err)
+		}
+		if id < providerIDBase {
+			t.Errorf("hashID(%q) returned %d, which may be a JobFluent ID", text, id)
+		}
+	}
+
+	// JobFluent IDs must stay below the IDs of other boards.
+	if id, err := itemID(rssItem{GUID: strconv.Itoa(providerIDBase)}); err == nil {
+		t.Errorf("itemID() returned %d, which may be the ID of another board", id)
+	}
+}
+
+func TestFeedDecoderSkipsItems(t *testing.T) {
+	content := `<rss><channel>
+<item><title>No ID</title><pubDate>Wed, 01 May 2019 10:30:00 +0200</pubDate></item>
+<item><guid>1000</guid><title>Valid</title><pubDate>Wed, 01 May 2019 10:30:00 +0200</pubDate></item>
+<item><guid>2000</guid><title>No date</title><pubDate>yesterday</pubDate></item>
+</channel></rss>`
+	decoder, _ := DecoderForFormat(FeedFormatXML)
+	offers, err := decoder.Decode([]byte(content))
+	if err != nil {
+		t.Fatalf("A feed with some valid items should be decoded: %s", err)
+	}
+	if len(offers) != 1 || offers[0].ID != 1000 {
+		t.Errorf("Expected only the valid item, got %+v", offers)
+	}
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom">
+<entry><title>No ID</title><updated>2019-05-01T10:30:00Z</updated></entry>
+<entry><id>tag:jobs.example.com,2019:1</id><title>Valid</title><updated>2019-05-01T10:30:00Z</updated></entry>
+</feed>`
+	offers, err = feedDecoders[FeedFormatRSS].Decode([]byte(atom))
+	if err != nil {
+		t.Fatalf("An Atom feed with some valid entries should be decoded: %s", err)
+	}
+	if len(offers) != 1 || offers[0].Position != "Valid" {
+		t.Errorf("Expected only the valid entry, got %+v", offers)
+	}
+	invalid := strings.Replace(atom, "<id>", "<ignored>", -1)
+	if _, err := feedDecoders[FeedFormatRSS].Decode([]byte(invalid)); err == nil {
+		t.Errorf("An Atom feed without valid entries should fail")
+	}
+}
+
+func TestSyndicationFeedDecoder(t *testing.T) {
+	atom := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Other board</title>
+  <entry>
+    <title>Go Developer</title>
+    <id>tag:jobs.example.com,2019:go-developer</id>
+    <link rel="alternate" href="https://jobs.example.com/go-developer"/>
+    <published>2019-05-01T10:30:00+02:00</published>
+    <author><name>ActionSoft Inc</name></author>
+    <category term="golang"/>
+    <summary>We are looking for a Go developer.</summary>
+  </entry>
+</feed>`
+	rss := `<rss version="2.0"><channel><item>
+  <title>Go Developer</title>
+  <link>https://jobs.example.com/2019/05/go-developer</link>
+  <pubDate>Wed, 1 May 2019 10:30:00 +0200</pubDate>
+  <author>ActionSoft Inc</author>
+  <category>golang</category>
+  <description>We are looking for a Go developer.</description>
+</item></channel></rss>`
+
+	decoder, err := DecoderForFormat(FeedFormatRSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"Atom": atom, "RSS": rss} {
+		offers, err := decoder.Decode([]byte(content))
+		if err != nil {
+			t.Errorf("Cannot decode %s feed: %s", name, err)
+			continue
+		}
+		if len(offers) != 1 {
+			t.Errorf("Expected 1 offer in %s feed, got %d", name, len(offers))
+			continue
+		}
+		offer := offers[0]
+		if offer.ID == 0 || offer.Position != "Go Developer" || offer.Company != "ActionSoft Inc" ||
+			len(offer.Tags) != 1 || offer.Tags[0] != "golang" || offer.CreationDate.Day() != 1 ||
+			offer.Description != "We are looking for a Go developer." || offer.URL == "" {
+			t.Errorf("%s offer was not decoded properly: %+v", name, offer)
+		}
+	}
+
+	// XHTML content is given as child elements, and HTML content escaped.
+	atom = `<feed xmlns="http://www.w3.org/2005/Atom"><entry>
+  <title>Go Developer</title>
+  <id>tag:jobs.example.com,2019:go-developer</id>
+  <updated>2019-05-01T10:30:00Z</updated>
+  <summary>Plain summary</summary>
+  <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Go dev</p></div></content>
+</entry><entry>
+  <title>Frontend Developer</title>
+  <id>tag:jobs.example.com,2019:frontend-developer</id>
+  <updated>2019-05-01T10:30:00Z</updated>
+  <content type="html">&lt;p&gt;Frontend dev&lt;/p&gt;</content>
+</entry></feed>`
+
+	offers, err := decoder.Decode([]byte(atom))
+	if err != nil {
+		t.Fatalf("Cannot decode Atom feed: %s", err)
+	}
+	if len(offers) != 2 {
+		t.Fatalf("Expected 2 offers, got %d", len(offers))
+	}
+	if xhtml := offers[0].Description; !strings.Contains(xhtml, "<p>Go dev</p>") {
+		t.Errorf("The XHTML content was not kept: %q", xhtml)
+	}
+	if html := offers[1].Description; html != "<p>Frontend dev</p>" {
+		t.Errorf("The HTML content was not unescaped: %q", html)
+	}
+}
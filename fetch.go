@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Location is an alias to the location type.
@@ -16,9 +20,17 @@ type LocationData struct {
 	title string
 	// Slug to use when fetching.
 	Slug string
+	// Format the feed is published in, such as FeedFormatJSON.
+	Format string
+	// URL of the feed, for providers that are not able to build it.
+	URL string
+
+	// The provider that publishes offers for this location.
+	provider Provider
 }
 
-// The list of locations that have available feeds.
+// The list of locations that have available feeds in JobFluent, unless a
+// different list is given in the config file.
 const (
 	LocationAmsterdam Location = iota
 	LocationBarcelona
@@ -29,22 +41,60 @@ const (
 	LocationRemote
 )
 
-// Locations hold the metadata for each location that has an available feed.
-var Locations = map[Location]LocationData{
-	LocationAmsterdam: {"Amsterdam", "amsterdam"},
-	LocationBarcelona: {"Barcelona", "barcelona"},
-	LocationBerlin:    {"Berlin", "berlin"},
-	LocationLondon:    {"London", "london"},
-	LocationMadrid:    {"Madrid", "madrid"},
-	LocationParis:     {"Paris", "paris"},
-	LocationRemote:    {"Remote", "remoto"},
+// jobFluentLocations holds the metadata for each location that has an
+// available feed in JobFluent.
+var jobFluentLocations = []LocationData{
+	LocationAmsterdam: {title: "Amsterdam", Slug: "amsterdam", Format: FeedFormatJSON},
+	LocationBarcelona: {title: "Barcelona", Slug: "barcelona", Format: FeedFormatJSON},
+	LocationBerlin:    {title: "Berlin", Slug: "berlin", Format: FeedFormatJSON},
+	LocationLondon:    {title: "London", Slug: "london", Format: FeedFormatJSON},
+	LocationMadrid:    {title: "Madrid", Slug: "madrid", Format: FeedFormatJSON},
+	LocationParis:     {title: "Paris", Slug: "paris", Format: FeedFormatJSON},
+	LocationRemote:    {title: "Remote", Slug: "remoto", Format: FeedFormatJSON},
+}
+
+// Locations hold the metadata for each location that has an available feed,
+// as given by the providers in use.
+var Locations = providerLocations(Providers)
+
+// SortedLocations returns every location that has an available feed, in
+// the order they were declared.
+func SortedLocations() []Location {
+	locations := make([]Location, 0, len(Locations))
+	for location := range Locations {
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i] < locations[j]
+	})
+	return locations
+}
+
+// LocationBySlug finds the location with the given slug. The title of the
+// location is also accepted, since slugs are not always in English. The
+// comparison is case insensitive.
+func LocationBySlug(slug string) (Location, bool) {
+	for location, data := range Locations {
+		if strings.EqualFold(data.Slug, slug) || strings.EqualFold(data.title, slug) {
+			return location, true
+		}
+	}
+	return 0, false
 }
 
-// TargetServer points to the HTTP server to use for fetching offers.
+// TargetServer points to the HTTP server to use for fetching offers, unless
+// a different one is set in the config file.
 const TargetServer = "https://www.jobfluent.com"
 
-// UserAgent keeps the user agent to be used in HTTP requests.
-const UserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.14; rv:66.0) Gecko/20100101 Firefox/66.0"
+// Version is the version of the application. Release builds set it using
+// -ldflags "-X main.Version=<version>".
+var Version = "dev"
+
+// DefaultUserAgent returns the user agent used in HTTP requests unless a
+// different one is set in the config file.
+func DefaultUserAgent() string {
+	return "jobflucli/" + Version
+}
 
 // unmarshalResponse will convert the bytearray content into offers.
 func unmarshalResponse(resp []byte) ([]Offer, error) {
@@ -56,50 +106,113 @@ func unmarshalResponse(resp []byte) ([]Offer, error) {
 	return offers, nil
 }
 
-func executeHTTPRequest(url string) ([]byte, error) {
-	// Let's be honest and use a real client so we can set UA header.
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot prepare HTTP request: %s", err)
+// executeHTTPRequest requests the given URL using the HTTP settings of the
+// config file, and returns the body and the validators of the response. If
+// validators are given, ErrNotModified is returned if the resource did not
+// change since they were received.
+func executeHTTPRequest(ctx context.Context, url string, known *Validators) ([]byte, *Validators, error) {
+	return httpClient.GetConditional(ctx, url, known)
+}
+
+// FetchOffers will ask the provider of the location to retrieve the
+// available offers.  This function will retrieve every offer in the list.
+// It is up to the application to filter this list before presenting the
+// results to the user.
+func FetchOffers(ctx context.Context, location Location) ([]Offer, error) {
+	data, ok := Locations[location]
+	if !ok {
+		return nil, fmt.Errorf("Invalid location")
+	}
+	offers, _, err := data.provider.FetchOffers(ctx, data, nil)
+	return offers, err
+}
+
+// LoadOffers retrieves the offers for the given location. Unless offline is
+// set, the feed is fetched from the network and a copy is stored in the
+// cache. The feed is only downloaded again if it changed since it was
+// cached, otherwise the cached offers are used. If the network fails, or if
+// offline is set, the cached copy of the feed is used instead. The returned
+// source tells which one was used. Cancelling the given context aborts the
+// request without using the cache.
+func LoadOffers(ctx context.Context, location Location, offline bool) ([]Offer, *FeedSource, error) {
+	data, ok := Locations[location]
+	if !ok {
+		return nil, nil, fmt.Errorf("Invalid location")
 	}
-	req.Header.Set("User-Agent", UserAgent)
+	slug := data.Slug
 
-	// Execute HTTP request.
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot execute HTTP request: %s", err)
+	var fetchErr error
+	if !offline {
+		var known *Validators
+		cached, err := ReadCache(slug)
+		if err == nil {
+			known = cached.Validators
+		}
+		var offers []Offer
+		var validators *Validators
+		offers, validators, fetchErr = data.provider.FetchOffers(ctx, data, known)
+		if fetchErr == nil {
+			// Failing to cache the offers is not worth bothering the user.
+			WriteCache(slug, offers, validators)
+			return offers, &FeedSource{FetchDate: time.Now()}, nil
+		}
+		if errors.Is(fetchErr, ErrNotModified) && cached != nil {
+			// The cached copy is as good as new.
+			WriteCache(slug, cached.Offers, cached.Validators)
+			return cached.Offers, &FeedSource{Unchanged: true, FetchDate: time.Now()}, nil
+		}
+		if ctx.Err() != nil {
+			// The user does not want to wait for the offers anymore.
+			return nil, nil, ctx.Err()
+		}
 	}
-	defer resp.Body.Close()
 
-	// Extract data from the URL response.
-	body, err := ioutil.ReadAll(resp.Body)
+	// Fall back to the cached copy of the feed.
+	entry, err := ReadCache(slug)
 	if err != nil {
-		return nil, fmt.Errorf("Cannot read HTTP response: %s", err)
+		if fetchErr != nil {
+			// The network error is more meaningful to the user.
+			return nil, nil, fetchErr
+		}
+		return nil, nil, err
 	}
+	return entry.Offers, &FeedSource{Cached: true, FetchDate: entry.FetchDate}, nil
+}
 
-	return body, nil
+// LocationOffers holds the offers that were retrieved for a location.
+type LocationOffers struct {
+	Location Location
+	Offers   []Offer
+	Source   *FeedSource
 }
 
-// FetchOffers will send an HTTP request to the feed URL and retrieve the
-// available offers found in the feed.  This function will retrieve every
-// offer in the list.  It is up to the application to filter this list
-// before presenting the results to the user.  There are multiple feeds,
-func FetchOffers(location Location) ([]Offer, error) {
-	// Get the real slug to use in the URL behind this location.
-	if _, ok := Locations[location]; !ok {
-		return nil, fmt.Errorf("Invalid location")
-	}
-	locData := Locations[location]
+// LoadLocations retrieves the offers for several locations concurrently,
+// following the same rules as LoadOffers. The results are returned in the
+// same order as the given locations. If any location fails, the first
+// error found is returned.
+func LoadLocations(ctx context.Context, locations []Location, offline bool) ([]LocationOffers, error) {
+	results := make([]LocationOffers, len(locations))
+	errs := make([]error, len(locations))
 
-	// Fetch the jobs.
-	url := fmt.Sprintf("%s/es/feeds/jobs-%s.json", TargetServer, locData.Slug)
-	content, err := executeHTTPRequest(url)
-	if err != nil {
-		// err is properly wrapped by executeHttpRequest().
-		return nil, err
+	var wg sync.WaitGroup
+	for i, location := range locations {
+		wg.Add(1)
+		go func(i int, location Location) {
+			defer wg.Done()
+			offers, source, err := LoadOffers(ctx, location, offline)
+			results[i] = LocationOffers{location, offers, source}
+			errs[i] = err
+		}(i, location)
 	}
+	wg.Wait()
 
-	// Offload the remains to the unmarshal process.
-	return unmarshalResponse(content)
+	for i, err := range errs {
+		if err != nil {
+			if len(locations) > 1 {
+				return nil, fmt.Errorf("%s: %s", Locations[locations[i]].title, err)
+			}
+			return nil, err
+		}
+	}
+	return results, nil
 }
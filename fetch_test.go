@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useTestCache points the cache to a temporary directory until the returned
+// function is called.
+func useTestCache(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "jobflucli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, set := os.LookupEnv("XDG_CACHE_HOME")
+	os.Setenv("XDG_CACHE_HOME", dir)
+	return func() {
+		if set {
+			os.Setenv("XDG_CACHE_HOME", previous)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// writeTestCache stores the given content as the cache file of a feed.
+func writeTestCache(t *testing.T, slug string, content []byte) {
+	path, err := cachePath(slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadOffersLegacyCache(t *testing.T) {
+	defer useTestCache(t)()
+
+	// Older versions stored XML feeds as a JSON string.
+	feed, err := ioutil.ReadFile(filepath.Join("testdata", "feed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	content, err := json.Marshal(map[string]interface{}{
+		"fetched": fetched,
+		"format":  FeedFormatXML,
+		"feed":    string(feed),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestCache(t, Locations[LocationBerlin].Slug, content)
+
+	offers, source, err := LoadOffers(context.Background(), LocationBerlin, true)
+	if err != nil {
+		t.Fatalf("LoadOffers() failed: %s", err)
+	}
+	if want := decodeFixture(t, "feed.xml", FeedFormatXML); !reflect.DeepEqual(offers, want) {
+		t.Errorf("The legacy cache was not decoded properly: %v", offers)
+	}
+	if !source.Cached || !source.FetchDate.Equal(fetched) {
+		t.Errorf("Unexpected source %+v", source)
+	}
+}
+
+func TestLoadOffersNotModified(t *testing.T) {
+	defer useTestCache(t)()
+	const etag = `"v1"`
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") != etag {
+			t.Errorf("The cached ETag was not sent: %q", r.Header.Get("If-None-Match"))
+		}
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+	defer func() {
+		config = defaultConfig()
+	}()
+	config.Server = server.URL
+
+	slug := Locations[LocationBerlin].Slug
+	known := &Validators{URL: config.FeedURL(slug, FeedFormatJSON), ETag: etag}
+	fetched := time.Now().Add(-time.Hour)
+	content, err := json.Marshal(CacheEntry{FetchDate: fetched, Offers: offers, Validators: known})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestCache(t, slug, content)
+
+	results, err := LoadLocations(context.Background(), []Location{LocationBerlin}, false)
+	if err != nil {
+		t.Fatalf("LoadLocations() failed: %s", err)
+	}
+	if sent := atomic.LoadInt32(&requests); sent != 1 {
+		t.Fatalf("Expected one conditional request, sent %d", sent)
+	}
+	result := results[0]
+	if !result.Source.Unchanged || result.Source.Cached || result.Source.String() != "Feed unchanged" {
+		t.Errorf("Unexpected source %+v", result.Source)
+	}
+	if len(result.Offers) != len(offers) || result.Offers[0].ID != offers[0].ID {
+		t.Errorf("The cached offers were not used: %v", result.Offers)
+	}
+
+	// The cache is refreshed, keeping the validators.
+	after, err := ReadCache(slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(after.Validators, known) || !after.FetchDate.After(fetched) {
+		t.Errorf("The cache was not refreshed properly: %+v", after)
+	}
+
+	// An unchanged feed is not a new fetch, so the new offers are kept.
+	state := &State{}
+	state.init()
+	state.Seen[slug] = []int{1000, 2000, 3000}
+	state.New[slug] = []int{3000}
+	ctx := &Context{state: state}
+	if err := ctx.UpdateLocationOffers(results); err != nil {
+		t.Fatalf("UpdateLocationOffers() failed: %s", err)
+	}
+	if !ctx.IsNew(3000) || ctx.IsNew(1000) || !reflect.DeepEqual(state.New[slug], []int{3000}) {
+		t.Errorf("The new offers were changed: %v", state.New)
+	}
+}
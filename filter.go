@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TagFilter restricts the offers presented to the user to those labelled
+// with a particular set of tags.
+type TagFilter struct {
+	// The tags that the offers should be labelled with.
+	Tags []string
+	// Whether every tag must be present in the offer (AND) or any of them
+	// is enough (OR).
+	MatchAll bool
+}
+
+// String describes the filter in a way that can be shown to the user.
+func (filter *TagFilter) String() string {
+	operator := " OR "
+	if filter.MatchAll {
+		operator = " AND "
+	}
+	return strings.Join(filter.Tags, operator)
+}
+
+// FilterPreset is a named set of criteria to filter offers, which is read
+// from the config file so that it does not have to be typed every time.
+type FilterPreset struct {
+	// The name used to pick the preset.
+	Name string `toml:"name"`
+	// The slugs of the locations to retrieve offers from, when the preset
+	// is used in a command and no location is given.
+	Locations []string `toml:"locations"`
+	// The offers must be labelled with these tags.
+	Tags []string `toml:"tags"`
+	// Whether every tag is required, or any of them is enough.
+	MatchAll bool `toml:"match_all"`
+	// The offers must not be labelled with any of these tags.
+	ExcludeTags []string `toml:"exclude_tags"`
+	// The offers must contain this text, like when searching.
+	Query string `toml:"query"`
+	// The offers must not be published by any of these companies. The
+	// comparison is case insensitive.
+	ExcludeCompanies []string `toml:"exclude_companies"`
+}
+
+// String describes the criteria of the preset.
+func (preset *FilterPreset) String() string {
+	var criteria []string
+	if len(preset.Tags) > 0 {
+		tags := TagFilter{Tags: preset.Tags, MatchAll: preset.MatchAll}
+		criteria = append(criteria, "tags: "+tags.String())
+	}
+	if len(preset.ExcludeTags) > 0 {
+		criteria = append(criteria, "not tags: "+strings.Join(preset.ExcludeTags, ", "))
+	}
+	if preset.Query != "" {
+		criteria = append(criteria, fmt.Sprintf("text: %q", preset.Query))
+	}
+	if len(preset.ExcludeCompanies) > 0 {
+		criteria = append(criteria, "not companies: "+strings.Join(preset.ExcludeCompanies, ", "))
+	}
+	if len(criteria) == 0 {
+		return "every offer"
+	}
+	return strings.Join(criteria, "; ")
+}
+
+// Compile converts the preset into a function that tells whether an offer
+// passes every criteria of the preset. The text function must return the
+// lowercased text to look the query into, as built by searchText.
+func (preset *FilterPreset) Compile(text func(offer Offer) string) func(offer Offer) bool {
+	include := lowercaseSet(preset.Tags)
+	exclude := lowercaseSet(preset.ExcludeTags)
+	companies := lowercaseSet(preset.ExcludeCompanies)
+	query := strings.ToLower(preset.Query)
+	matchAll := preset.MatchAll
+
+	return func(offer Offer) bool {
+		if companies[strings.ToLower(strings.TrimSpace(offer.Company))] {
+			return false
+		}
+		found := 0
+		for _, tag := range offer.Tags {
+			tag = strings.ToLower(tag)
+			if exclude[tag] {
+				return false
+			}
+			if include[tag] {
+				found++
+			}
+		}
+		if len(include) > 0 && (found == 0 || matchAll && found < len(include)) {
+			return false
+		}
+		if query != "" && !strings.Contains(text(offer), query) {
+			return false
+		}
+		return true
+	}
+}
+
+// lowercaseSet builds a set with the lowercased version of each value.
+func lowercaseSet(values []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, value := range values {
+		set[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+	return set
+}
+
+// HideList holds the rules used to hide the offers the user never wants to
+// see again, such as those published by some recruiters.
+type HideList struct {
+	// Companies whose offers are hidden. The comparison is case insensitive.
+	Companies []string `json:"companies"`
+	// Regular expressions matched against the company of the offers, which
+	// are hidden if any of them matches. The match is case insensitive.
+	CompanyPatterns []string `json:"company_patterns"`
+	// The IDs of individual offers that are hidden.
+	Offers map[int]bool `json:"offers"`
+
+	// The compiled version of each pattern, nil if it is not valid.
+	compiled []*regexp.Regexp
+}
+
+// Hides tells whether the given offer is hidden by any rule of the list.
+func (list *HideList) Hides(offer Offer) bool {
+	if list.Offers[offer.ID] {
+		return true
+	}
+	company := strings.TrimSpace(offer.Company)
+	for _, hidden := range list.Companies {
+		if strings.EqualFold(hidden, company) {
+			return true
+		}
+	}
+	for _, pattern := range list.patterns() {
+		if pattern != nil && pattern.MatchString(company) {
+			return true
+		}
+	}
+	return false
+}
+
+// patterns returns the compiled company patterns. Patterns that are not
+// valid, which may happen if the state file was edited by hand, are nil.
+func (list *HideList) patterns() []*regexp.Regexp {
+	if len(list.compiled) != len(list.CompanyPatterns) {
+		list.compiled = make([]*regexp.Regexp, len(list.CompanyPatterns))
+		for i, pattern := range list.CompanyPatterns {
+			list.compiled[i], _ = compileCompanyPattern(pattern)
+		}
+	}
+	return list.compiled
+}
+
+// compileCompanyPattern compiles a pattern matched against companies.
+func compileCompanyPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// AddCompany hides the offers of the companies described by the rule. The
+// rule is either the name of a company, or a regular expression between
+// slashes, such as /recruit(ing|ers)/.
+func (list *HideList) AddCompany(rule string) error {
+	rule = strings.TrimSpace(rule)
+	if len(rule) > 2 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/") {
+		pattern := rule[1 : len(rule)-1]
+		if _, err := compileCompanyPattern(pattern); err != nil {
+			return fmt.Errorf("Invalid company pattern: %s", err)
+		}
+		list.CompanyPatterns = append(list.CompanyPatterns, pattern)
+		list.compiled = nil
+		return nil
+	}
+	if rule == "" {
+		return fmt.Errorf("The company to hide cannot be empty")
+	}
+	for _, hidden := range list.Companies {
+		if strings.EqualFold(hidden, rule) {
+			return nil
+		}
+	}
+	list.Companies = append(list.Companies, rule)
+	return nil
+}
+
+// RemoveCompany removes every rule that hides the offers of the given
+// company, and returns how many rules were removed.
+func (list *HideList) RemoveCompany(company string) int {
+	company = strings.TrimSpace(company)
+	removed := 0
+	companies := list.Companies[:0]
+	for _, hidden := range list.Companies {
+		if strings.EqualFold(hidden, company) {
+			removed++
+		} else {
+			companies = append(companies, hidden)
+		}
+	}
+	list.Companies = companies
+
+	var patterns []string
+	for i, pattern := range list.CompanyPatterns {
+		if compiled := list.patterns()[i]; compiled != nil && compiled.MatchString(company) {
+			removed++
+		} else {
+			patterns = append(patterns, pattern)
+		}
+	}
+	list.CompanyPatterns = patterns
+	list.compiled = nil
+	return removed
+}
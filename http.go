@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPConfig holds the settings used to send HTTP requests.
+type HTTPConfig struct {
+	// How long a request may take, including reading the response.
+	Timeout Duration `toml:"timeout"`
+	// How many times a request is sent again after a transient failure.
+	Retries int `toml:"retries"`
+	// How long to wait before the first retry. The delay doubles after
+	// every retry.
+	RetryDelay Duration `toml:"retry_delay"`
+	// The largest response accepted, in bytes.
+	MaxResponseSize int64 `toml:"max_response_size"`
+	// The User-Agent header sent with every request. When empty,
+	// DefaultUserAgent is used.
+	UserAgent string `toml:"user_agent"`
+	// The URL of the proxy that requests are sent through. When empty, the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used.
+	Proxy string `toml:"proxy"`
+	// Other headers sent with every request.
+	Headers map[string]string `toml:"headers"`
+	// The path to a file with PEM encoded certificates that are trusted
+	// besides the ones of the system, such as the certificate of a proxy
+	// that intercepts TLS connections.
+	CABundle string `toml:"ca_bundle"`
+}
+
+// defaultHTTPConfig returns the HTTP settings used when there is no config
+// file.
+func defaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout:         Duration{30 * time.Second},
+		Retries:         2,
+		RetryDelay:      Duration{time.Second},
+		MaxResponseSize: 10 << 20,
+	}
+}
+
+// ErrNotModified is returned by conditional requests when the server tells
+// that the resource did not change since it was downloaded.
+var ErrNotModified = errors.New("Feed unchanged")
+
+// Validators identify the version of a resource that was downloaded, so
+// that the server can tell whether it changed when it is requested again.
+type Validators struct {
+	// The URL the resource was downloaded from.
+	URL string `json:"url"`
+	// The ETag header of the response.
+	ETag string `json:"etag,omitempty"`
+	// The Last-Modified header of the response.
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// HTTPStatusError is returned when the server answers a request with a
+// status code that does not mean success.
+type HTTPStatusError struct {
+	// The URL that was requested.
+	URL string
+	// The status code of the response, such as 503.
+	StatusCode int
+	// The status line of the response, such as "503 Service Unavailable".
+	Status string
+}
+
+func (err *HTTPStatusError) Error() string {
+	return fmt.Sprintf("Server answered %s for %s", err.Status, err.URL)
+}
+
+// temporary tells whether sending the request again may succeed.
+func (err *HTTPStatusError) temporary() bool {
+	switch err.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// HTTPClient sends GET requests, retrying those that fail for reasons that
+// may be transient, such as network errors or an overloaded server.
+type HTTPClient struct {
+	client   *http.Client
+	settings HTTPConfig
+}
+
+// NewHTTPClient builds a client that uses the given settings. It fails if
+// the proxy URL or the CA bundle are not valid.
+func NewHTTPClient(settings HTTPConfig) (*HTTPClient, error) {
+	transport, err := newTransport(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPClient{
+		client:   &http.Client{Timeout: settings.Timeout.Duration, Transport: transport},
+		settings: settings,
+	}, nil
+}
+
+// newTransport builds the transport used to send requests, which goes
+// through the configured proxy and trusts the configured certificates.
+func newTransport(settings HTTPConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if settings.Proxy != "" {
+		proxy, err := url.Parse(settings.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("Invalid HTTP proxy %q", settings.Proxy)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("Unsupported HTTP proxy scheme %q", proxy.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if settings.CABundle != "" {
+		pem, err := ioutil.ReadFile(settings.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read CA bundle: %s", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			// The system certificates are not available on every platform.
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in CA bundle %s", settings.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return transport, nil
+}
+
+// Get requests the given URL and returns the body of the response. Failed
+// requests are retried with exponential backoff, unless the failure is not
+// transient or the context is cancelled.
+func (c *HTTPClient) Get(ctx context.Context, url string) ([]byte, error) {
+	body, _, err := c.GetConditional(ctx, url, nil)
+	return body, err
+}
+
+// GetConditional works like Get, but if validators for the same URL are
+// given, the server is asked to send the resource only if it changed. If it
+// did not, ErrNotModified is returned. Otherwise, the validators of the new
+// response are returned, or nil if the server gave none.
+func (c *HTTPClient) GetConditional(ctx context.Context, url string, known *Validators) ([]byte, *Validators, error) {
+	if known != nil && known.URL != url {
+		// The validators belong to a different resource.
+		known = nil
+	}
+	delay := c.settings.RetryDelay.Duration
+	for attempt := 0; ; attempt++ {
+		body, validators, temporary, err := c.get(ctx, url, known)
+		if err == nil || !temporary || attempt >= c.settings.Retries || ctx.Err() != nil {
+			return body, validators, err
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return nil, nil, err
+		}
+	}
+}
+
+// get sends a single request to the URL. Besides the body and the
+// validators of the response, it tells whether a failure may be transient.
+func (c *HTTPClient) get(ctx context.Context, url string, known *Validators) ([]byte, *Validators, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("Cannot prepare HTTP request: %s", err)
+	}
+	userAgent := c.settings.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent()
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for name, value := range c.settings.Headers {
+		req.Header.Set(name, value)
+	}
+	if known != nil && known.ETag != "" {
+		req.Header.Set("If-None-Match", known.ETag)
+	}
+	if known != nil && known.LastModified != "" {
+		req.Header.Set("If-Modified-Since", known.LastModified)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, true, fmt.Errorf("Cannot execute HTTP request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && known != nil {
+		return nil, nil, false, ErrNotModified
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Drain a bit of the body, so that the connection can be reused.
+		io.CopyN(ioutil.Discard, resp.Body, 4096)
+		statusErr := &HTTPStatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+		return nil, nil, statusErr.temporary(), statusErr
+	}
+
+	// Read one byte more than allowed to know whether the limit is exceeded.
+	limit := c.settings.MaxResponseSize
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, nil, true, fmt.Errorf("Cannot read HTTP response: %s", err)
+	}
+	if int64(len(body)) > limit {
+		return nil, nil, false, fmt.Errorf("HTTP response from %s is larger than %d bytes", url, limit)
+	}
+
+	var validators *Validators
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag != "" || lastModified != "" {
+		validators = &Validators{URL: url, ETag: etag, LastModified: lastModified}
+	}
+	return body, validators, false, nil
+}
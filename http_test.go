@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testHTTPConfig returns settings that do not make the tests wait.
+func testHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Timeout:         Duration{time.Second},
+		Retries:         2,
+		RetryDelay:      Duration{time.Millisecond},
+		MaxResponseSize: 1024,
+	}
+}
+
+// newTestClient builds a client with the given settings, failing the test
+// if that is not possible.
+func newTestClient(t *testing.T, settings HTTPConfig) *HTTPClient {
+	client, err := NewHTTPClient(settings)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() failed: %s", err)
+	}
+	return client
+}
+
+// failingServer answers with the given status code to the first failures
+// requests, and with the given body afterwards. It counts the requests.
+func failingServer(status, failures int, body string, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(requests, 1) <= int32(failures) {
+			http.Error(w, "<html>Oops</html>", status)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestHTTPClientSuccess(t *testing.T) {
+	var requests int32
+	server := failingServer(0, 0, "[]", &requests)
+	defer server.Close()
+
+	body, err := newTestClient(t, testHTTPConfig()).Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %s", err)
+	}
+	if sent := atomic.LoadInt32(&requests); string(body) != "[]" || sent != 1 {
+		t.Errorf("Get() returned %q after %d requests", body, sent)
+	}
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	cases := []struct {
+		status   int
+		failures int
+		want     int32
+		fails    bool
+	}{
+		{http.StatusServiceUnavailable, 2, 3, false},
+		{http.StatusTooManyRequests, 1, 2, false},
+		{http.StatusBadGateway, 3, 3, true},
+		{http.StatusNotFound, 1, 1, true},
+		{http.StatusForbidden, 1, 1, true},
+	}
+
+	for _, c := range cases {
+		var requests int32
+		server := failingServer(c.status, c.failures, "[]", &requests)
+		_, err := newTestClient(t, testHTTPConfig()).Get(context.Background(), server.URL)
+		server.Close()
+
+		if sent := atomic.LoadInt32(&requests); sent != c.want {
+			t.Errorf("Status %d: sent %d requests, expected %d", c.status, sent, c.want)
+		}
+		if c.fails {
+			statusErr, ok := err.(*HTTPStatusError)
+			if !ok {
+				t.Errorf("Status %d: expected an HTTPStatusError, got %v", c.status, err)
+				continue
+			}
+			if statusErr.StatusCode != c.status || !strings.Contains(err.Error(), http.StatusText(c.status)) {
+				t.Errorf("Status %d: unexpected error %s", c.status, err)
+			}
+		} else if err != nil {
+			t.Errorf("Status %d: Get() failed: %s", c.status, err)
+		}
+	}
+}
+
+func TestHTTPClientBackoff(t *testing.T) {
+	var mutex sync.Mutex
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		times = append(times, time.Now())
+		mutex.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	settings := testHTTPConfig()
+	settings.RetryDelay = Duration{20 * time.Millisecond}
+	if _, err := newTestClient(t, settings).Get(context.Background(), server.URL); err == nil {
+		t.Fatalf("Get() should fail")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(times) != 3 {
+		t.Fatalf("Sent %d requests, expected 3", len(times))
+	}
+	if first, second := times[1].Sub(times[0]), times[2].Sub(times[1]); first < 20*time.Millisecond || second < 40*time.Millisecond {
+		t.Errorf("Retries were not delayed exponentially: %s, %s", first, second)
+	}
+}
+
+func TestHTTPClientMaxResponseSize(t *testing.T) {
+	var requests int32
+	server := failingServer(0, 0, strings.Repeat("x", 1025), &requests)
+	defer server.Close()
+
+	if _, err := newTestClient(t, testHTTPConfig()).Get(context.Background(), server.URL); err == nil {
+		t.Errorf("Get() should reject responses larger than the limit")
+	}
+	if sent := atomic.LoadInt32(&requests); sent != 1 {
+		t.Errorf("Large responses should not be retried, sent %d requests", sent)
+	}
+
+	settings := testHTTPConfig()
+	settings.MaxResponseSize = 1025
+	if _, err := newTestClient(t, settings).Get(context.Background(), server.URL); err != nil {
+		t.Errorf("Get() should accept responses as large as the limit: %s", err)
+	}
+}
+
+func TestHTTPClientTimeout(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	settings := testHTTPConfig()
+	settings.Timeout = Duration{20 * time.Millisecond}
+	settings.Retries = 1
+	start := time.Now()
+	if _, err := newTestClient(t, settings).Get(context.Background(), server.URL); err == nil {
+		t.Errorf("Get() should time out")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Get() took %s despite the timeout", elapsed)
+	}
+	if sent := atomic.LoadInt32(&requests); sent != 2 {
+		t.Errorf("Timeouts should be retried, sent %d requests", sent)
+	}
+}
+
+func TestHTTPClientCancel(t *testing.T) {
+	var requests int32
+	server := failingServer(http.StatusServiceUnavailable, 10, "[]", &requests)
+	defer server.Close()
+
+	settings := testHTTPConfig()
+	settings.RetryDelay = Duration{time.Minute}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := newTestClient(t, settings).Get(ctx, server.URL); err == nil {
+		t.Errorf("Get() should fail when cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get() kept waiting for %s after being cancelled", elapsed)
+	}
+}
+
+func TestHTTPClientConditional(t *testing.T) {
+	const etag = `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, "[]")
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, testHTTPConfig())
+	body, validators, err := client.GetConditional(context.Background(), server.URL, nil)
+	if err != nil {
+		t.Fatalf("GetConditional() failed: %s", err)
+	}
+	if string(body) != "[]" || validators == nil || validators.ETag != etag || validators.URL != server.URL {
+		t.Fatalf("GetConditional() returned %q and %v", body, validators)
+	}
+
+	if _, _, err := client.GetConditional(context.Background(), server.URL, validators); err != ErrNotModified {
+		t.Errorf("Expected ErrNotModified, got %v", err)
+	}
+
+	// Validators of a different URL must not be sent.
+	other := &Validators{URL: server.URL + "/other", ETag: etag}
+	if body, _, err := client.GetConditional(context.Background(), server.URL, other); err != nil || string(body) != "[]" {
+		t.Errorf("GetConditional() returned %q, %v for validators of another URL", body, err)
+	}
+}
+
+func TestHTTPClientHeaders(t *testing.T) {
+	// The server answers with the headers it received.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("User-Agent"), r.Header.Get("X-Token"))
+	}))
+	defer server.Close()
+
+	body, err := newTestClient(t, testHTTPConfig()).Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %s", err)
+	}
+	if string(body) != DefaultUserAgent()+"|" {
+		t.Errorf("Expected the default User-Agent, got %q", body)
+	}
+
+	settings := testHTTPConfig()
+	settings.UserAgent = "custom/1.0"
+	settings.Headers = map[string]string{"X-Token": "secret"}
+	body, err = newTestClient(t, settings).Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Get() failed: %s", err)
+	}
+	if string(body) != "custom/1.0|secret" {
+		t.Errorf("Configured headers were not sent: %q", body)
+	}
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	// The proxy answers with the URL it was asked for.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	settings := testHTTPConfig()
+	settings.Proxy = proxy.URL
+	body, err := newTestClient(t, settings).Get(context.Background(), "http://jobs.example.com/feed.json")
+	if err != nil {
+		t.Fatalf("Get() failed: %s", err)
+	}
+	if string(body) != "http://jobs.example.com/feed.json" {
+		t.Errorf("The request was not sent through the proxy: %q", body)
+	}
+
+	for _, invalid := range []string{"localhost:3128", "ftp://localhost:3128"} {
+		settings.Proxy = invalid
+		if _, err := NewHTTPClient(settings); err == nil {
+			t.Errorf("Expected proxy %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "[]")
+	}))
+	defer server.Close()
+
+	if _, err := newTestClient(t, testHTTPConfig()).Get(context.Background(), server.URL); err == nil {
+		t.Errorf("Expected the certificate of the server not to be trusted")
+	}
+
+	dir, err := ioutil.TempDir("", "jobflucli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bundle := filepath.Join(dir, "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(bundle, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := testHTTPConfig()
+	settings.CABundle = bundle
+	if _, err := newTestClient(t, settings).Get(context.Background(), server.URL); err != nil {
+		t.Errorf("Get() failed with the CA bundle: %s", err)
+	}
+
+	settings.CABundle = filepath.Join(dir, "missing.pem")
+	if _, err := NewHTTPClient(settings); err == nil {
+		t.Errorf("Expected a missing CA bundle to be rejected")
+	}
+}
@@ -4,11 +4,50 @@
 // leaving your warm terminal.
 package main
 
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// fail tells the user about an error and exits the application.
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "jobflucli: %s\n", err)
+	os.Exit(1)
+}
+
 func main() {
-	context := new(Context)
+	offline := flag.Bool("offline", false, "use the cached feeds instead of the network")
+	configPath := flag.String("config", "", "config file to use instead of the default one")
+	refresh := flag.Duration("refresh", 0, "fetch the offers again in background every `interval`, such as 15m")
+	flag.Usage = usage
+	flag.Parse()
+
+	if err := LoadConfig(*configPath); err != nil {
+		fail(err)
+	}
+	if *refresh > 0 {
+		config.RefreshInterval.Duration = *refresh
+	}
+
+	// Subcommands do not need the terminal user interface.
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Arg(0), flag.Args()[1:], *offline); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	state, err := LoadState()
+	if err != nil {
+		fail(err)
+	}
+
+	context := &Context{offline: *offline, state: state}
 	ui := NewUserInterface(context)
 	ui.SwitchToLocations()
 	if err := ui.Run(); err != nil {
-		panic(err)
+		// By now the terminal has been restored by the application.
+		fail(err)
 	}
 }
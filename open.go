@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// browserCommand builds the command used to open the given URL. The browser
+// setting of the config file is used first, then the BROWSER environment
+// variable, and then the opener of the system. In the first two cases, %s
+// is replaced by the URL, or the URL is appended if there is no %s.
+func browserCommand(url string) *exec.Cmd {
+	browser := config.Browser
+	if browser == "" {
+		// BROWSER may list several browsers to try, use the first one.
+		browser = strings.Split(os.Getenv("BROWSER"), string(os.PathListSeparator))[0]
+	}
+	if browser = strings.TrimSpace(browser); browser != "" {
+		if runtime.GOOS == "windows" {
+			// cmd.exe expands %VAR% even inside quotes, so the browser is
+			// run directly instead, with the URL as its own argument.
+			args := browserArgs(browser, url)
+			return exec.Command(args[0], args[1:]...)
+		}
+		if strings.Contains(browser, "%s") {
+			browser = strings.Replace(browser, "%s", shellQuote(url), -1)
+		} else {
+			browser += " " + shellQuote(url)
+		}
+		return exec.Command("sh", "-c", browser)
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		return exec.Command("open", url)
+	default:
+		return exec.Command("xdg-open", url)
+	}
+}
+
+// browserArgs splits the browser command into the program and its
+// arguments, replacing %s by the URL or appending the URL if there is no
+// %s. Arguments that contain spaces, such as the path to the program, can
+// be enclosed in double quotes. The browser must not be blank.
+func browserArgs(browser, url string) []string {
+	var args []string
+	var arg strings.Builder
+	quoted, inArg := false, false
+	for _, r := range browser {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	replaced := false
+	for i := range args {
+		if strings.Contains(args[i], "%s") {
+			args[i] = strings.Replace(args[i], "%s", url, -1)
+			replaced = true
+		}
+	}
+	if !replaced {
+		args = append(args, url)
+	}
+	return args
+}
+
+// shellQuote quotes the text so that it is given as a single argument to
+// a POSIX shell, whatever characters it contains.
+func shellQuote(text string) string {
+	return "'" + strings.Replace(text, "'", `'\''`, -1) + "'"
+}
+
+// OpenURL opens the given URL in a browser. It does not wait for the browser
+// to be closed. The output of the browser is discarded, so that it does not
+// mess with the terminal.
+func OpenURL(url string) error {
+	if url == "" {
+		return fmt.Errorf("The offer has no URL")
+	}
+	cmd := browserCommand(url)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("Cannot open browser: %s", err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// osc52Sequence builds the escape sequence that asks the terminal to put the
+// text in the clipboard. Since it travels with the output of the
+// application, it works over SSH as long as the terminal supports it. Inside
+// tmux, the sequence is wrapped so that tmux passes it to the terminal.
+func osc52Sequence(text string) string {
+	sequence := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x1b\\"
+	if os.Getenv("TMUX") != "" {
+		sequence = "\x1bPtmux;" + strings.Replace(sequence, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+	}
+	return sequence
+}
+
+// CopyToClipboard puts the given text in the clipboard of the terminal
+// connected to out.
+func CopyToClipboard(out io.Writer, text string) error {
+	if _, err := io.WriteString(out, osc52Sequence(text)); err != nil {
+		return fmt.Errorf("Cannot copy to clipboard: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// setEnv sets an environment variable until the returned function is
+// called, which restores its previous value.
+func setEnv(key, value string) func() {
+	previous, set := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if set {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The quoting of cmd.exe is not tested")
+	}
+	cases := []string{
+		"https://example.com/offer/1000",
+		"https://example.com/?q=it's",
+		"https://example.com/;touch /tmp/pwned",
+		"https://example.com/$(touch /tmp/pwned)",
+		"https://example.com/`id`&b=$HOME|cat",
+		"'; rm -rf ~; echo '",
+	}
+	for _, text := range cases {
+		// The shell must print the text as given, without running anything.
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(text)).Output()
+		if err != nil {
+			t.Fatalf("The shell failed with %q: %s", text, err)
+		}
+		if string(out) != text {
+			t.Errorf("shellQuote(%q) was read by the shell as %q", text, out)
+		}
+	}
+}
+
+func TestBrowserArgs(t *testing.T) {
+	const url = "https://example.com/?q=%PATH%&a=^b\"c"
+	cases := []struct {
+		browser string
+		want    []string
+	}{
+		{"firefox", []string{"firefox", url}},
+		{"firefox --new-tab %s", []string{"firefox", "--new-tab", url}},
+		{"  lynx   %s  --dump ", []string{"lynx", url, "--dump"}},
+		{`"C:\Program Files\Mozilla Firefox\firefox.exe" %s`, []string{`C:\Program Files\Mozilla Firefox\firefox.exe`, url}},
+		{`browser --open=%s --title "Job offer"`, []string{"browser", "--open=" + url, "--title", "Job offer"}},
+	}
+	for _, c := range cases {
+		if args := browserArgs(c.browser, url); strings.Join(args, "\x00") != strings.Join(c.want, "\x00") {
+			t.Errorf("browserArgs(%q) returned %q, expected %q", c.browser, args, c.want)
+		}
+	}
+}
+
+func TestOSC52Sequence(t *testing.T) {
+	payload := base64.StdEncoding.EncodeToString([]byte("https://example.com/ñ"))
+
+	defer setEnv("TMUX", "")()
+	plain := "\x1b]52;c;" + payload + "\x1b\\"
+	if sequence := osc52Sequence("https://example.com/ñ"); sequence != plain {
+		t.Errorf("Unexpected sequence %q, expected %q", sequence, plain)
+	}
+
+	os.Setenv("TMUX", "/tmp/tmux-1000/default,1234,0")
+	wrapped := "\x1bPtmux;\x1b\x1b]52;c;" + payload + "\x1b\x1b\\\x1b\\"
+	if sequence := osc52Sequence("https://example.com/ñ"); sequence != wrapped {
+		t.Errorf("Unexpected sequence %q inside tmux, expected %q", sequence, wrapped)
+	}
+}
+
+func TestBrowserCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Browser commands are run by cmd.exe")
+	}
+	defer func() {
+		config = defaultConfig()
+	}()
+	const url = "https://example.com/offer/1000"
+
+	cases := []struct {
+		setting string
+		env     string
+		want    []string
+	}{
+		{"", "firefox" + string(os.PathListSeparator) + "chromium", []string{"sh", "-c", "firefox '" + url + "'"}},
+		{"", "lynx %s --dump", []string{"sh", "-c", "lynx '" + url + "' --dump"}},
+		{"w3m", "firefox", []string{"sh", "-c", "w3m '" + url + "'"}},
+		{"open -a Safari %s", "firefox", []string{"sh", "-c", "open -a Safari '" + url + "'"}},
+	}
+	for _, c := range cases {
+		config.Browser = c.setting
+		restore := setEnv("BROWSER", c.env)
+		args := browserCommand(url).Args
+		restore()
+		if strings.Join(args, "\x00") != strings.Join(c.want, "\x00") {
+			t.Errorf("Browser %q with BROWSER=%q ran %q, expected %q", c.setting, c.env, args, c.want)
+		}
+	}
+
+	// Without settings, the opener of the system is used.
+	config.Browser = ""
+	defer setEnv("BROWSER", "")()
+	if args := browserCommand(url).Args; args[len(args)-1] != url {
+		t.Errorf("The URL was not given to the system opener: %q", args)
+	}
+}
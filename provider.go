@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Provider is a source of job offers, such as a job board, which publishes
+// offers for a set of locations.
+type Provider interface {
+	// Name returns the name presented to the user for this provider.
+	Name() string
+	// Locations returns the locations that offers are published for.
+	Locations() []LocationData
+	// FetchOffers retrieves the offers currently published for a location.
+	// If the validators of a previous fetch are given, ErrNotModified is
+	// returned if the offers did not change since then. The validators of
+	// this fetch are returned, if any. The request is aborted if the given
+	// context is cancelled.
+	FetchOffers(ctx context.Context, location LocationData, known *Validators) ([]Offer, *Validators, error)
+}
+
+// Providers is the list of providers that offers are retrieved from, in the
+// order they are presented to the user.
+var Providers = []Provider{NewJobFluentProvider(jobFluentLocations)}
+
+// SetProviders puts the given providers in use, replacing the list of
+// locations with the locations of the providers.
+func SetProviders(providers []Provider) {
+	Providers = providers
+	Locations = providerLocations(providers)
+}
+
+// providerLocations numbers the locations of every provider, in the order
+// the providers are given.
+func providerLocations(providers []Provider) map[Location]LocationData {
+	locations := make(map[Location]LocationData)
+	for _, provider := range providers {
+		for _, location := range provider.Locations() {
+			location.provider = provider
+			locations[Location(len(locations))] = location
+		}
+	}
+	return locations
+}
+
+// JobFluentProvider retrieves offers from the feeds published by JobFluent,
+// using the server, path and language given in the config file.
+type JobFluentProvider struct {
+	locations []LocationData
+}
+
+// NewJobFluentProvider returns a provider for the JobFluent feeds of the
+// given locations.
+func NewJobFluentProvider(locations []LocationData) *JobFluentProvider {
+	return &JobFluentProvider{locations: locations}
+}
+
+// Name returns the name of JobFluent.
+func (provider *JobFluentProvider) Name() string {
+	return "JobFluent"
+}
+
+// Locations returns the locations that JobFluent publishes feeds for.
+func (provider *JobFluentProvider) Locations() []LocationData {
+	return provider.locations
+}
+
+// FetchOffers sends an HTTP request to the feed URL of the location and
+// decodes the offers using the format of the feed.
+func (provider *JobFluentProvider) FetchOffers(ctx context.Context, location LocationData, known *Validators) ([]Offer, *Validators, error) {
+	decoder, err := DecoderForFormat(location.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+	// err is properly wrapped by executeHttpRequest().
+	content, validators, err := executeHTTPRequest(ctx, config.FeedURL(location.Slug, location.Format), known)
+	if err != nil {
+		return nil, nil, err
+	}
+	offers, err := decoder.Decode(content)
+	return offers, validators, err
+}
+
+// FeedProvider retrieves offers from RSS or Atom feeds given by URL, such as
+// the feeds published by other job boards. Each feed is presented as a
+// location.
+type FeedProvider struct {
+	name  string
+	feeds []LocationData
+}
+
+// NewFeedProvider returns a provider with the given name for the given
+// feeds. The URL of each feed must be set.
+func NewFeedProvider(name string, feeds []LocationData) *FeedProvider {
+	return &FeedProvider{name: name, feeds: feeds}
+}
+
+// Name returns the name given to the provider.
+func (provider *FeedProvider) Name() string {
+	return provider.name
+}
+
+// Locations returns the feeds of the provider.
+func (provider *FeedProvider) Locations() []LocationData {
+	return provider.feeds
+}
+
+// FetchOffers sends an HTTP request to the URL of the feed and decodes the
+// offers, whether the feed uses RSS or Atom.
+func (provider *FeedProvider) FetchOffers(ctx context.Context, location LocationData, known *Validators) ([]Offer, *Validators, error) {
+	if location.URL == "" {
+		return nil, nil, fmt.Errorf("The feed %s has no URL", location.title)
+	}
+	content, validators, err := executeHTTPRequest(ctx, location.URL, known)
+	if err != nil {
+		return nil, nil, err
+	}
+	offers, err := syndicationFeedDecoder{}.Decode(content)
+	return offers, validators, err
+}
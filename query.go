@@ -0,0 +1,431 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query is a filter expression that has been parsed and can be evaluated
+// against offers. Expressions are made of terms combined using the AND, OR
+// and NOT operators and parenthesis. Terms next to each other are also
+// combined using AND. The operators must be written in uppercase.
+//
+// A term can be a bare word or a quoted string, which is searched in every
+// field of the offer like the incremental search, or a field condition:
+//
+//	tag:golang            the offer is labelled with the tag
+//	tag~go                any tag of the offer contains the text
+//	company:"Foo Inc"     the company is exactly this one (ignoring case)
+//	company~foo           the company contains the text
+//	position:... / ~...   same, for the position
+//	description~remote    the description contains the text (only ~)
+//	posted:<7d            the offer was posted less than 7 days ago
+//	posted:>2w            the offer was posted more than 2 weeks ago
+//	posted:<2019-05-01    the offer was posted before the date
+//	posted:2019-05-01     the offer was posted that day
+//	id:1234               the offer has this ID
+//
+// Durations accept the m, h, d and w units.
+type Query struct {
+	source string
+	match  queryMatcher
+}
+
+// QueryError tells why a filter expression could not be parsed.
+type QueryError struct {
+	// The position of the expression at which the error was found.
+	Pos int
+	// What went wrong.
+	Message string
+}
+
+func (err *QueryError) Error() string {
+	return fmt.Sprintf("Filter error at column %d: %s", err.Pos+1, err.Message)
+}
+
+// queryMatcher tells whether an offer passes a part of an expression.
+type queryMatcher func(offer Offer, env *queryEnv) bool
+
+// queryEnv holds the information needed to evaluate an expression.
+type queryEnv struct {
+	// Returns the lowercased text to search into, as built by searchText.
+	text func(offer Offer) string
+	// Returns the lowercased cleaned description, as built by
+	// searchDescription.
+	description func(offer Offer) string
+	// The moment used to compute the age of the offers.
+	now time.Time
+}
+
+// ParseQuery parses the given filter expression. If the expression is not
+// valid, a *QueryError is returned.
+func ParseQuery(source string) (*Query, error) {
+	tokens, err := lexQuery(source)
+	if err != nil {
+		return nil, err
+	}
+	parser := &queryParser{tokens: tokens}
+	match, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := parser.peek(); next.kind != tokenEOF {
+		return nil, &QueryError{next.pos, fmt.Sprintf("unexpected %s", next)}
+	}
+	return &Query{source: source, match: match}, nil
+}
+
+// String returns the expression the query was parsed from.
+func (query *Query) String() string {
+	return query.source
+}
+
+// Matches tells whether the offer passes the query. The text function must
+// return the lowercased text to search bare words into, as built by
+// searchText, and the description function the lowercased description, as
+// built by searchDescription.
+func (query *Query) Matches(offer Offer, text, description func(offer Offer) string) bool {
+	return query.match(offer, &queryEnv{text: text, description: description, now: time.Now()})
+}
+
+// The kinds of token found in a filter expression.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenColon
+	tokenTilde
+	tokenLess
+	tokenGreater
+)
+
+// queryToken is a piece of a filter expression.
+type queryToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (token queryToken) String() string {
+	switch token.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return fmt.Sprintf("%q", token.text)
+	default:
+		return fmt.Sprintf("'%s'", token.text)
+	}
+}
+
+// lexQuery splits a filter expression into tokens.
+func lexQuery(source string) ([]queryToken, error) {
+	var tokens []queryToken
+	symbols := map[rune]tokenKind{
+		'(': tokenLParen,
+		')': tokenRParen,
+		':': tokenColon,
+		'~': tokenTilde,
+		'<': tokenLess,
+		'>': tokenGreater,
+	}
+
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			i++
+		case symbols[r] != tokenEOF:
+			tokens = append(tokens, queryToken{symbols[r], string(r), i})
+			i++
+		case r == '"':
+			start := i
+			i++
+			var text strings.Builder
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+				i++
+			}
+			if i == len(runes) {
+				return nil, &QueryError{start, "missing closing quote"}
+			}
+			i++
+			tokens = append(tokens, queryToken{tokenString, text.String(), start})
+		default:
+			start := i
+			for i < len(runes) && !strings.ContainsRune(" \t\n\"", runes[i]) && symbols[runes[i]] == tokenEOF {
+				i++
+			}
+			tokens = append(tokens, queryToken{tokenWord, string(runes[start:i]), start})
+		}
+	}
+	return append(tokens, queryToken{tokenEOF, "", len(runes)}), nil
+}
+
+// queryParser builds a matcher out of the tokens of a filter expression.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+// isKeyword tells whether the token is the given operator.
+func (token queryToken) isKeyword(keyword string) bool {
+	return token.kind == tokenWord && token.text == keyword
+}
+
+// parseOr parses terms combined with OR.
+func (p *queryParser) parseOr() (queryMatcher, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orMatcher(left, right)
+	}
+	return left, nil
+}
+
+// parseAnd parses terms combined with AND, or just next to each other.
+func (p *queryParser) parseAnd() (queryMatcher, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		next := p.peek()
+		if next.isKeyword("AND") {
+			p.next()
+		} else if next.kind == tokenEOF || next.kind == tokenRParen || next.isKeyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andMatcher(left, right)
+	}
+}
+
+func orMatcher(left, right queryMatcher) queryMatcher {
+	return func(offer Offer, env *queryEnv) bool {
+		return left(offer, env) || right(offer, env)
+	}
+}
+
+func andMatcher(left, right queryMatcher) queryMatcher {
+	return func(offer Offer, env *queryEnv) bool {
+		return left(offer, env) && right(offer, env)
+	}
+}
+
+// parseNot parses a term that may be negated.
+func (p *queryParser) parseNot() (queryMatcher, error) {
+	if !p.peek().isKeyword("NOT") {
+		return p.parsePrimary()
+	}
+	p.next()
+	term, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return func(offer Offer, env *queryEnv) bool {
+		return !term(offer, env)
+	}, nil
+}
+
+// parsePrimary parses a term or an expression between parenthesis.
+func (p *queryParser) parsePrimary() (queryMatcher, error) {
+	token := p.next()
+	switch {
+	case token.kind == tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &QueryError{closing.pos, fmt.Sprintf("expected ')' to close the '(' at column %d, found %s", token.pos+1, closing)}
+		}
+		return inner, nil
+	case token.kind == tokenString:
+		return textMatcher(token.text), nil
+	case token.kind == tokenWord && (token.text == "AND" || token.text == "OR"):
+		return nil, &QueryError{token.pos, fmt.Sprintf("expected a term before %s", token)}
+	case token.kind == tokenWord:
+		if next := p.peek(); next.kind == tokenColon || next.kind == tokenTilde {
+			return p.parseField(token)
+		}
+		return textMatcher(token.text), nil
+	default:
+		return nil, &QueryError{token.pos, fmt.Sprintf("expected a term, found %s", token)}
+	}
+}
+
+// textMatcher matches offers containing the text in any field.
+func textMatcher(text string) queryMatcher {
+	text = strings.ToLower(text)
+	return func(offer Offer, env *queryEnv) bool {
+		return strings.Contains(env.text(offer), text)
+	}
+}
+
+// parseField parses a condition on a field of the offer, such as tag:golang.
+func (p *queryParser) parseField(field queryToken) (queryMatcher, error) {
+	operator := p.next()
+	name := strings.ToLower(field.text)
+	exact := operator.kind == tokenColon
+
+	// Only dates can be compared.
+	var comparison queryToken
+	if next := p.peek(); next.kind == tokenLess || next.kind == tokenGreater {
+		comparison = p.next()
+		if name != "posted" {
+			return nil, &QueryError{comparison.pos, fmt.Sprintf("%s cannot be compared, only posted can", name)}
+		}
+	}
+
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, &QueryError{value.pos, fmt.Sprintf("expected a value after %s%s, found %s", field.text, operator.text, value)}
+	}
+	text := strings.ToLower(value.text)
+
+	switch name {
+	case "tag":
+		return func(offer Offer, env *queryEnv) bool {
+			for _, tag := range offer.Tags {
+				tag = strings.ToLower(tag)
+				if exact && tag == text || !exact && strings.Contains(tag, text) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "company":
+		return stringMatcher(func(offer Offer) string { return offer.Company }, text, exact), nil
+	case "position":
+		return stringMatcher(func(offer Offer) string { return offer.Position }, text, exact), nil
+	case "description":
+		// Descriptions are too long to be matched exactly.
+		if exact {
+			return nil, &QueryError{operator.pos, "description must be followed by '~'"}
+		}
+		return func(offer Offer, env *queryEnv) bool {
+			return strings.Contains(env.description(offer), text)
+		}, nil
+	case "text":
+		return textMatcher(text), nil
+	case "id":
+		id, err := strconv.Atoi(value.text)
+		if err != nil {
+			return nil, &QueryError{value.pos, fmt.Sprintf("%s is not a valid offer ID", value)}
+		}
+		return func(offer Offer, env *queryEnv) bool {
+			return offer.ID == id
+		}, nil
+	case "posted":
+		if !exact {
+			return nil, &QueryError{operator.pos, "posted must be followed by ':'"}
+		}
+		return parsePosted(comparison, value)
+	default:
+		return nil, &QueryError{field.pos, fmt.Sprintf("unknown field %s, expected tag, company, position, description, text, posted or id", field)}
+	}
+}
+
+// stringMatcher matches offers whose field is equal to, or contains, the
+// given lowercased text.
+func stringMatcher(field func(offer Offer) string, text string, exact bool) queryMatcher {
+	return func(offer Offer, env *queryEnv) bool {
+		value := strings.ToLower(strings.TrimSpace(field(offer)))
+		if exact {
+			return value == text
+		}
+		return strings.Contains(value, text)
+	}
+}
+
+// parsePosted builds a condition on the creation date of the offers. The
+// value is either an age, such as 7d, or a date, such as 2019-05-01.
+func parsePosted(comparison, value queryToken) (queryMatcher, error) {
+	if date, err := time.ParseInLocation("2006-01-02", value.text, time.Local); err == nil {
+		switch comparison.kind {
+		case tokenLess:
+			return func(offer Offer, env *queryEnv) bool {
+				return offer.CreationDate.Before(date)
+			}, nil
+		case tokenGreater:
+			return func(offer Offer, env *queryEnv) bool {
+				return !offer.CreationDate.Before(date.AddDate(0, 0, 1))
+			}, nil
+		default:
+			return func(offer Offer, env *queryEnv) bool {
+				return !offer.CreationDate.Before(date) && offer.CreationDate.Before(date.AddDate(0, 0, 1))
+			}, nil
+		}
+	}
+
+	age, err := parseAge(value.text)
+	if err != nil {
+		return nil, &QueryError{value.pos, fmt.Sprintf("%s is not a valid age or date, try 7d or 2019-05-01", value)}
+	}
+	switch comparison.kind {
+	case tokenLess:
+		return func(offer Offer, env *queryEnv) bool {
+			return env.now.Sub(offer.CreationDate) < age
+		}, nil
+	case tokenGreater:
+		return func(offer Offer, env *queryEnv) bool {
+			return env.now.Sub(offer.CreationDate) > age
+		}, nil
+	default:
+		return nil, &QueryError{value.pos, fmt.Sprintf("ages must be compared using < or >, such as posted:<%s", value.text)}
+	}
+}
+
+// parseAge parses a duration such as 30m, 12h, 7d or 2w.
+func parseAge(text string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+	if len(text) < 2 {
+		return 0, fmt.Errorf("invalid age %q", text)
+	}
+	unit, ok := units[text[len(text)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid age %q", text)
+	}
+	amount, err := strconv.Atoi(text[:len(text)-1])
+	if err != nil || amount < 0 {
+		return 0, fmt.Errorf("invalid age %q", text)
+	}
+	return time.Duration(amount) * unit, nil
+}
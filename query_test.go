@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryMatches(t *testing.T) {
+	cases := []struct {
+		query string
+		want  []int
+	}{
+		{"tag:one", []int{1000, 2000}},
+		{"tag:ONE", []int{1000, 2000}},
+		{"tag:on", []int{}},
+		{"tag~on", []int{1000, 2000}},
+		{"tag:one AND tag:two", []int{2000}},
+		{"tag:one tag:two", []int{2000}},
+		{"tag:alpha OR tag:gamma", []int{1000, 3000}},
+		{"NOT tag:one", []int{3000}},
+		{"NOT NOT tag:one", []int{1000, 2000}},
+		{"tag:two AND NOT company:\"ActionWare Inc\"", []int{2000}},
+		{"company:actionware", []int{}},
+		{"company~actionware", []int{3000}},
+		{"position~LEAD", []int{3000}},
+		{"position:\"code ops\"", []int{2000}},
+		{"description~\"number 2\"", []int{2000}},
+		{"rockstar", []int{1000}},
+		{"\"offer number\"", []int{1000, 2000, 3000}},
+		{"text:security", []int{3000}},
+		{"id:2000", []int{2000}},
+		{"(tag:alpha OR tag:beta) AND tag:two", []int{2000}},
+		{"tag:alpha OR tag:beta AND tag:two", []int{1000, 2000}},
+		{"posted:<1d", []int{1000, 2000, 3000}},
+		{"posted:>1d", []int{}},
+	}
+
+	context := new(Context)
+	context.SetOffers(offers)
+
+	for _, c := range cases {
+		query, err := ParseQuery(c.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) failed: %s", c.query, err)
+			continue
+		}
+		context.SetQuery(query)
+		matched := context.FilteredOffers()
+		if len(matched) != len(c.want) {
+			t.Errorf("Query %q matched %d offers, expected %d", c.query, len(matched), len(c.want))
+			continue
+		}
+		for i, offer := range matched {
+			if offer.ID != c.want[i] {
+				t.Errorf("Query %q matched offer %d, expected %d", c.query, offer.ID, c.want[i])
+			}
+		}
+	}
+}
+
+func TestQueryPosted(t *testing.T) {
+	now := time.Now()
+	dated := []Offer{
+		Offer{ID: 1, CreationDate: now.Add(-2 * time.Hour)},
+		Offer{ID: 2, CreationDate: now.AddDate(0, 0, -10)},
+		Offer{ID: 3, CreationDate: time.Date(2019, 5, 1, 12, 0, 0, 0, time.Local)},
+	}
+	cases := []struct {
+		query string
+		want  int
+	}{
+		{"posted:<3h", 1},
+		{"posted:<1w", 1},
+		{"posted:>1w", 2},
+		{"posted:<30m", 0},
+		{"posted:<2019-05-01", 0},
+		{"posted:<2019-05-02", 1},
+		{"posted:>2019-05-01", 2},
+		{"posted:2019-05-01", 1},
+	}
+
+	context := new(Context)
+	context.SetOffers(dated)
+
+	for _, c := range cases {
+		query, err := ParseQuery(c.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) failed: %s", c.query, err)
+			continue
+		}
+		context.SetQuery(query)
+		if matched := len(context.FilteredOffers()); matched != c.want {
+			t.Errorf("Query %q matched %d offers, expected %d", c.query, matched, c.want)
+		}
+	}
+}
+
+func TestQuerySyntaxErrors(t *testing.T) {
+	cases := []struct {
+		query string
+		pos   int
+	}{
+		{"", 0},
+		{"tag:", 4},
+		{"tag:golang AND", 14},
+		{"AND tag:golang", 0},
+		{"tag:golang OR OR", 14},
+		{"(tag:golang", 11},
+		{"tag:golang)", 10},
+		{"company:\"Foo", 8},
+		{"salary:100", 0},
+		{"id:abc", 3},
+		{"posted:<7x", 8},
+		{"posted:7d", 7},
+		{"posted~7d", 6},
+		{"tag:<golang", 4},
+		{"description:remote", 11},
+	}
+
+	for _, c := range cases {
+		_, err := ParseQuery(c.query)
+		if err == nil {
+			t.Errorf("ParseQuery(%q) should have failed", c.query)
+			continue
+		}
+		queryErr, ok := err.(*QueryError)
+		if !ok {
+			t.Errorf("ParseQuery(%q) returned %T, expected *QueryError", c.query, err)
+			continue
+		}
+		if queryErr.Pos != c.pos {
+			t.Errorf("ParseQuery(%q) failed at %d, expected %d: %s", c.query, queryErr.Pos, c.pos, err)
+		}
+	}
+}
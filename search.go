@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"strings"
+)
+
+// searchDescription cleans the description of the offer and lowercases it,
+// so that searches are case insensitive. Cleaning is slow, so it should be
+// done once per offer.
+func searchDescription(offer Offer) string {
+	return strings.ToLower(cleanContent(offer.Description))
+}
+
+// searchText builds the text that is looked into when searching offers. It
+// contains the position, the company, the tags and the given description,
+// as built by searchDescription, lowercased so that searches are case
+// insensitive.
+func searchText(offer Offer, description string) string {
+	fields := []string{
+		offer.Position,
+		offer.Company,
+		strings.Join(offer.Tags, " "),
+	}
+	return strings.ToLower(strings.Join(fields, "\n")) + "\n" + description
+}
+
+// findMatches returns the byte ranges where the query appears in the text,
+// ignoring case. Overlapping occurrences are not reported.
+func findMatches(text, query string) [][2]int {
+	lowerText := strings.ToLower(text)
+	lowerQuery := strings.ToLower(query)
+	if lowerQuery == "" || len(lowerText) != len(text) {
+		// Lowercasing some runes changes their length, and the positions
+		// found would not point to the original text anymore.
+		return nil
+	}
+
+	var matches [][2]int
+	for start := 0; start < len(lowerText); {
+		index := strings.Index(lowerText[start:], lowerQuery)
+		if index < 0 {
+			break
+		}
+		begin := start + index
+		end := begin + len(lowerQuery)
+		matches = append(matches, [2]int{begin, end})
+		start = end
+	}
+	return matches
+}
+
+// highlightMatches escapes the text so that it can be presented in a widget
+// that supports color tags, and paints every occurrence of the query.
+func highlightMatches(text, query string) string {
+	out, _ := markMatches(text, query, false)
+	return out
+}
+
+// markMatches works like highlightMatches, but if regions is true it will
+// also wrap every occurrence in a numbered region, so that text views can
+// scroll to them. It returns the number of occurrences found.
+func markMatches(text, query string, regions bool) (string, int) {
+	matches := findMatches(text, query)
+	var out strings.Builder
+	last := 0
+	for i, match := range matches {
+		out.WriteString(tview.Escape(text[last:match[0]]))
+		if regions {
+			fmt.Fprintf(&out, `["%d"]`, i)
+		}
+		out.WriteString("[black:yellow]")
+		out.WriteString(tview.Escape(text[match[0]:match[1]]))
+		out.WriteString("[-:-]")
+		if regions {
+			out.WriteString(`[""]`)
+		}
+		last = match[1]
+	}
+	out.WriteString(tview.Escape(text[last:]))
+	return out.String(), len(matches)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// State holds the information about offers that is kept across sessions.
+type State struct {
+	// The IDs of the offers that have been opened by the user.
+	Read map[int]bool `json:"read"`
+	// The offers starred by the user, kept in full since they may disappear
+	// from the feeds.
+	Starred map[int]Offer `json:"starred"`
+	// The IDs of the offers found in the latest fetch of each location,
+	// keyed by the slug of the location.
+	Seen map[string][]int `json:"seen"`
+	// The IDs of the offers that were not present in the fetch before the
+	// latest one, keyed by the slug of the location.
+	New map[string][]int `json:"new"`
+	// The rules used to hide offers.
+	Hidden HideList `json:"hidden"`
+
+	// Where the state is stored.
+	path string
+}
+
+// statePath returns the path to the state file with the given name. State
+// files are kept in the XDG state directory on UNIX systems, and in the user
+// config directory on systems that have no such thing.
+func statePath(name string) (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("Cannot locate state directory: %s", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+		if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+			if dir, err = os.UserConfigDir(); err != nil {
+				return "", fmt.Errorf("Cannot locate state directory: %s", err)
+			}
+		}
+	}
+	return filepath.Join(dir, "jobflucli", name), nil
+}
+
+// LoadState reads the state stored by previous sessions. If there is no
+// state yet, an empty state is returned.
+func LoadState() (*State, error) {
+	return loadStateFile("state.json")
+}
+
+// LoadWatchState reads the state stored by previous runs of the watch
+// command. It is kept apart from the state of the interactive sessions, so
+// that both can run at the same time.
+func LoadWatchState() (*State, error) {
+	return loadStateFile("watch.json")
+}
+
+// loadStateFile reads the state file with the given name. If the file does
+// not exist yet, an empty state is returned.
+func loadStateFile(name string) (*State, error) {
+	path, err := statePath(name)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{path: path}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		state.init()
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read state file: %s", err)
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("Cannot parse state file %s: %s", path, err)
+	}
+	state.init()
+	return state, nil
+}
+
+// init makes sure that every field in the state can be used.
+func (state *State) init() {
+	if state.Read == nil {
+		state.Read = make(map[int]bool)
+	}
+	if state.Starred == nil {
+		state.Starred = make(map[int]Offer)
+	}
+	if state.Seen == nil {
+		state.Seen = make(map[string][]int)
+	}
+	if state.New == nil {
+		state.New = make(map[string][]int)
+	}
+	if state.Hidden.Offers == nil {
+		state.Hidden.Offers = make(map[int]bool)
+	}
+}
+
+// Save writes the state to disk so that it can be loaded by later sessions.
+func (state *State) Save() error {
+	if state.path == "" {
+		// This state is not backed by a file.
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(state.path), 0755); err != nil {
+		return fmt.Errorf("Cannot create state directory: %s", err)
+	}
+	content, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("Cannot encode state: %s", err)
+	}
+	// Write to a temporary file first, so that a crash never leaves a
+	// half-written state behind.
+	temp := state.path + ".tmp"
+	if err := ioutil.WriteFile(temp, content, 0644); err != nil {
+		return fmt.Errorf("Cannot write state file: %s", err)
+	}
+	if err := os.Rename(temp, state.path); err != nil {
+		return fmt.Errorf("Cannot write state file: %s", err)
+	}
+	return nil
+}
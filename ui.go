@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
+	"os"
+	"strings"
+	"time"
 )
 
 // UserInterface represents the TUI used by this application.
@@ -12,14 +17,27 @@ type UserInterface struct {
 	titleWidget  *tview.TextView
 	pagesWidget  *tview.Pages
 	statusWidget *tview.TextView
+	promptWidget *tview.InputField
 
 	// Page widgets
 	locationsList  *LocationsTable
 	jobOffersList  *OfferList
 	jobOfferDetail *OfferView
+	tagsList       *TagsTable
+	errorDialog    *ErrorDialog
+	savedList      *OfferList
+	presetsList    *PresetsTable
+
+	// The page to go back to when leaving the offer page.
+	offerReturnPage string
 
 	// Flex layout
 	layout *tview.Flex
+
+	// Cancels the fetch in progress, nil if nothing is being fetched.
+	cancelFetch func()
+	// Cancels the background refresh in progress, nil if not refreshing.
+	cancelRefresh func()
 }
 
 func (ui *UserInterface) applyTheme() {
@@ -28,6 +46,7 @@ func (ui *UserInterface) applyTheme() {
 	ui.statusWidget.SetBackgroundColor(tcell.ColorGreen)
 	ui.statusWidget.SetTextColor(tcell.ColorYellow)
 	ui.jobOffersList.SetSelectedStyle(tcell.ColorWhite, tcell.ColorBlue, tcell.AttrNone)
+	ui.savedList.SetSelectedStyle(tcell.ColorWhite, tcell.ColorBlue, tcell.AttrNone)
 }
 
 func (ui *UserInterface) globalApplicationKeybidings(event *tcell.EventKey) *tcell.EventKey {
@@ -35,6 +54,10 @@ func (ui *UserInterface) globalApplicationKeybidings(event *tcell.EventKey) *tce
 		ui.application.Draw()
 		return nil
 	}
+	if event.Key() == tcell.KeyEscape && ui.cancelFetch != nil {
+		ui.cancelFetch()
+		return nil
+	}
 	return event
 }
 
@@ -49,6 +72,7 @@ func NewUserInterface(context *Context) *UserInterface {
 
 		titleWidget:  titleLabel,
 		statusWidget: statusLabel,
+		promptWidget: tview.NewInputField(),
 
 		pagesWidget: tview.NewPages(),
 
@@ -57,22 +81,61 @@ func NewUserInterface(context *Context) *UserInterface {
 		locationsList:  NewLocationsTable(),
 		jobOffersList:  NewOfferList(),
 		jobOfferDetail: NewOfferView(),
+		tagsList:       NewTagsTable(),
+		errorDialog:    NewErrorDialog(),
+		savedList:      NewOfferList(),
+		presetsList:    NewPresetsTable(),
 	}
 
+	ui.jobOffersList.SetFilterFunc(ui.context.Matches)
+	ui.jobOffersList.SetFlagsFunc(ui.offerFlags)
+	ui.savedList.SetFlagsFunc(ui.offerFlags)
+
+	ui.savedList.SetSelectedFunc(func(row, col int) {
+		// Saved offers may not be in the feed anymore, use the stored copy.
+		offer := ui.context.GetStarredOffer(ui.savedList.GetSelectedOfferID())
+		if offer == nil {
+			return
+		}
+		ui.SwitchToOffer(offer)
+	})
+
+	ui.savedList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'q' {
+			ui.SwitchToLocations()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 's' {
+			if offer := ui.context.GetStarredOffer(ui.savedList.GetSelectedOfferID()); offer != nil {
+				ui.toggleStarred(*offer)
+				ui.savedList.SetOfferList(ui.context.StarredOffers())
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && (event.Rune() == 'o' || event.Rune() == 'O') {
+			ui.changeSort(ui.savedList, event.Rune() == 'O')
+			return nil
+		}
+		return event
+	})
+
 	ui.jobOffersList.SetSelectedFunc(func(row, col int) {
 		// Get the selected offer by looking the reverse map.
 		offerID := ui.jobOffersList.backingOfferIds[row]
 		offer := ui.context.GetOffer(offerID)
+		if offer == nil {
+			// Either a company row is selected, or the filters left the
+			// list empty.
+			ui.jobOffersList.ToggleSelectedGroup()
+			return
+		}
 		ui.SwitchToOffer(offer)
 	})
 
 	ui.locationsList.SetSelectedFunc(func(row, col int) {
 		// Get the location and fetch offers for that location.
-		location := ui.locationsList.GetSelectedLocation()
-		if err := ui.context.SetOffersByLocation(location); err != nil {
-			panic(err)
-		}
-		ui.SwitchToList()
+		locations := ui.locationsList.GetSelectedLocations()
+		ui.loadLocations(locations, ui.context.offline)
 	})
 
 	ui.locationsList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -80,6 +143,14 @@ func NewUserInterface(context *Context) *UserInterface {
 			ui.application.Stop()
 			return event
 		}
+		if event.Key() == tcell.KeyRune && event.Rune() == ' ' {
+			ui.locationsList.ToggleSelected()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'S' {
+			ui.SwitchToSaved()
+			return nil
+		}
 		return event
 	})
 
@@ -92,12 +163,170 @@ func NewUserInterface(context *Context) *UserInterface {
 			ui.SwitchToLocations()
 			return event
 		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 't' {
+			ui.SwitchToTags()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'p' {
+			ui.SwitchToPresets()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'c' {
+			ui.context.ClearFilters()
+			ui.SwitchToList()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == '/' {
+			ui.promptSearch()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'f' {
+			ui.promptQuery()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'e' {
+			ui.promptExport(ui.context.FilteredOffers())
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'N' {
+			ui.toggleRead()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 's' {
+			if offer := ui.context.GetOffer(ui.jobOffersList.GetSelectedOfferID()); offer != nil {
+				ui.toggleStarred(*offer)
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && (event.Rune() == 'o' || event.Rune() == 'O') {
+			ui.changeSort(ui.jobOffersList, event.Rune() == 'O')
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'd' {
+			if offer := ui.context.GetOffer(ui.jobOffersList.GetSelectedOfferID()); offer != nil {
+				ui.toggleHidden(*offer)
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'D' {
+			if offer := ui.context.GetOffer(ui.jobOffersList.GetSelectedOfferID()); offer != nil {
+				ui.toggleHiddenCompany(*offer)
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'H' {
+			ui.context.SetShowHidden(!ui.context.showHidden)
+			ui.SwitchToList()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'g' {
+			ui.jobOffersList.SetGrouped(!ui.jobOffersList.IsGrouped())
+			if ui.jobOffersList.IsGrouped() {
+				ui.SetStatus("Offers grouped by company")
+			} else {
+				ui.SetStatus("Offers not grouped")
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == ' ' {
+			ui.jobOffersList.ToggleSelectedGroup()
+			return nil
+		}
+		if event.Key() == tcell.KeyTab {
+			if !ui.jobOffersList.SelectNextOffer(func(id int) bool { return !ui.context.IsRead(id) }) {
+				ui.SetStatus("No unread offers")
+			}
+			return nil
+		}
 		return event
 	})
 
-	ui.jobOfferDetail.descriptionWidget.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+	ui.presetsList.SetSelectedFunc(func(row, col int) {
+		ui.context.SetPreset(ui.presetsList.GetSelectedPreset())
+		ui.SwitchToList()
+	})
+
+	ui.presetsList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyRune && event.Rune() == 'q' {
 			ui.SwitchToList()
+			return nil
+		}
+		return event
+	})
+
+	ui.tagsList.SetSelectedFunc(func(row, col int) {
+		// Apply the marked tags as the new filter for the offer list.
+		ui.context.SetTagFilter(ui.tagsList.GetFilter())
+		ui.SwitchToList()
+	})
+
+	ui.tagsList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'q' {
+			ui.SwitchToList()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == ' ' {
+			ui.tagsList.ToggleSelected()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'a' {
+			ui.tagsList.ToggleMatchAll()
+			ui.setTagsTitle()
+			return nil
+		}
+		return event
+	})
+
+	ui.jobOfferDetail.descriptionWidget.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'q' {
+			if ui.offerReturnPage == "saved" {
+				ui.SwitchToSaved()
+			} else {
+				ui.SwitchToList()
+			}
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 's' {
+			ui.toggleStarred(*ui.jobOfferDetail.offer)
+			ui.setOfferTitle()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'd' {
+			ui.toggleHidden(*ui.jobOfferDetail.offer)
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'o' {
+			if err := OpenURL(ui.jobOfferDetail.offer.URL); err != nil {
+				ui.SetStatus(err.Error())
+			} else {
+				ui.SetStatus("Opening " + ui.jobOfferDetail.offer.URL)
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'y' {
+			ui.copyToClipboard("URL", ui.jobOfferDetail.offer.URL)
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'Y' {
+			ui.copyToClipboard("Description", cleanContent(ui.jobOfferDetail.offer.Description))
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'D' {
+			ui.toggleHiddenCompany(*ui.jobOfferDetail.offer)
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'e' {
+			ui.promptExport([]Offer{*ui.jobOfferDetail.offer})
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'n' {
+			ui.jobOfferDetail.NextMatch()
+			ui.SetStatus(ui.jobOfferDetail.MatchStatus())
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'N' {
+			ui.jobOfferDetail.PrevMatch()
+			ui.SetStatus(ui.jobOfferDetail.MatchStatus())
+			return nil
 		}
 		return event
 	})
@@ -105,6 +334,10 @@ func NewUserInterface(context *Context) *UserInterface {
 	ui.pagesWidget.AddPage("locations", ui.locationsList, true, false)
 	ui.pagesWidget.AddPage("list", ui.jobOffersList, true, false)
 	ui.pagesWidget.AddPage("detail", ui.jobOfferDetail, true, false)
+	ui.pagesWidget.AddPage("tags", ui.tagsList, true, false)
+	ui.pagesWidget.AddPage("saved", ui.savedList, true, false)
+	ui.pagesWidget.AddPage("presets", ui.presetsList, true, false)
+	ui.pagesWidget.AddPage("error", ui.errorDialog, true, false)
 	ui.pagesWidget.ShowPage("list")
 	ui.application.SetFocus(ui.jobOffersList)
 
@@ -132,27 +365,461 @@ func (ui *UserInterface) SetStatus(status string) {
 	})
 }
 
+// Prompt replaces the status bar with an input field so that the user can
+// type some text. The changed function, if not nil, is called as the user
+// types. The done function is called when the user leaves the input field,
+// telling whether the text was accepted or the prompt was cancelled.
+func (ui *UserInterface) Prompt(label, text string, changed func(text string), done func(text string, accepted bool)) {
+	previousFocus := ui.application.GetFocus()
+
+	ui.promptWidget.SetChangedFunc(nil)
+	ui.promptWidget.SetLabel(label)
+	ui.promptWidget.SetText(text)
+	ui.promptWidget.SetChangedFunc(changed)
+	ui.promptWidget.SetDoneFunc(func(key tcell.Key) {
+		ui.layout.RemoveItem(ui.promptWidget)
+		ui.layout.AddItem(ui.statusWidget, 1, 1, false)
+		ui.application.SetFocus(previousFocus)
+		done(ui.promptWidget.GetText(), key != tcell.KeyEscape)
+	})
+
+	ui.layout.RemoveItem(ui.statusWidget)
+	ui.layout.AddItem(ui.promptWidget, 1, 1, true)
+	ui.application.SetFocus(ui.promptWidget)
+}
+
+// promptSearch asks for the text to search in the list of offers. The list
+// is filtered as the user types. Cancelling the prompt removes the search.
+func (ui *UserInterface) promptSearch() {
+	update := func(text string) {
+		ui.context.SetSearchQuery(text)
+		ui.jobOffersList.SetHighlight(text)
+	}
+	ui.Prompt("/", ui.context.searchQuery, update, func(text string, accepted bool) {
+		if !accepted {
+			update("")
+		}
+		ui.SwitchToList()
+	})
+}
+
+// promptQuery asks for a filter expression to apply to the list of offers.
+// If the expression is not valid, the filter in use is kept and the error
+// is presented in the status bar. Accepting an empty expression removes
+// the filter.
+func (ui *UserInterface) promptQuery() {
+	text := ""
+	if ui.context.query != nil {
+		text = ui.context.query.String()
+	}
+	ui.Prompt("Filter: ", text, nil, func(text string, accepted bool) {
+		if !accepted {
+			ui.SwitchToList()
+			return
+		}
+		if strings.TrimSpace(text) == "" {
+			ui.context.SetQuery(nil)
+			ui.SwitchToList()
+			return
+		}
+		query, err := ParseQuery(text)
+		if err != nil {
+			ui.SwitchToList()
+			ui.SetStatus(err.Error())
+			return
+		}
+		ui.context.SetQuery(query)
+		ui.SwitchToList()
+	})
+}
+
+// loadLocations retrieves the offers for the given locations in background,
+// so that the interface does not freeze while waiting for the network. A
+// spinner is presented in the status bar until the offers are available,
+// and the fetch can be cancelled by pressing Esc. If offline is set, the
+// offers are retrieved from the cache. This function should be called in
+// the main thread.
+func (ui *UserInterface) loadLocations(locations []Location, offline bool) {
+	if ui.cancelFetch != nil {
+		// There is already a fetch in progress.
+		return
+	}
+	if ui.cancelRefresh != nil {
+		// The user is not interested in the refreshed offers anymore.
+		ui.cancelRefresh()
+		ui.cancelRefresh = nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelFetch = cancel
+
+	type result struct {
+		offers []LocationOffers
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		offers, err := LoadLocations(ctx, locations, offline)
+		results <- result{offers, err}
+	}()
+
+	names := strings.Join(locationTitles(locations), ", ")
+	go func() {
+		spinner := []rune{'|', '/', '-', '\\'}
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			select {
+			case <-ticker.C:
+				ui.SetStatus(fmt.Sprintf("%c Fetching offers for %s...   Esc:Cancel", spinner[frame%len(spinner)], names))
+			case res := <-results:
+				ui.application.QueueUpdateDraw(func() {
+					ui.cancelFetch = nil
+					cancel()
+					if res.err != nil {
+						if ctx.Err() != nil {
+							ui.SwitchToLocations()
+							ui.SetStatus("Fetch cancelled")
+							return
+						}
+						ui.showError(locations, res.err)
+						return
+					}
+					err := ui.context.SetLocationOffers(res.offers)
+					ui.SwitchToList()
+					if err != nil {
+						ui.SetStatus(err.Error())
+					}
+				})
+				return
+			}
+		}
+	}()
+}
+
+// autoRefresh fetches the offers in the context again every time the given
+// interval elapses, until the application stops.
+func (ui *UserInterface) autoRefresh(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ui.application.QueueUpdate(ui.refreshLocations)
+	}
+}
+
+// refreshLocations fetches in background the offers for the locations in
+// the context, and merges them keeping the filters and the selected offer.
+// The user is told about new offers by flashing the status bar. Nothing
+// happens if there are no offers yet or if the user is fetching offers.
+// This function should be called in the main thread.
+func (ui *UserInterface) refreshLocations() {
+	if len(ui.context.locations) == 0 || ui.cancelFetch != nil || ui.cancelRefresh != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelRefresh = cancel
+
+	locations := ui.context.locations
+	offline := ui.context.offline
+	go func() {
+		results, err := LoadLocations(ctx, locations, offline)
+		ui.application.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				// Cancelled by a fetch started by the user.
+				return
+			}
+			ui.cancelRefresh = nil
+			cancel()
+			if err != nil {
+				ui.SetStatus("Cannot refresh offers: " + err.Error())
+				return
+			}
+
+			previous := make(map[int]bool)
+			for _, offer := range ui.context.offers {
+				previous[offer.ID] = true
+			}
+			selected := ui.jobOffersList.GetSelectedOfferID()
+
+			stateErr := ui.context.UpdateLocationOffers(results)
+			ui.jobOffersList.SetOfferList(ui.context.offers)
+			ui.jobOffersList.SelectOffer(selected)
+			if stateErr != nil {
+				ui.SetStatus(stateErr.Error())
+				return
+			}
+
+			added := 0
+			for _, offer := range ui.context.offers {
+				if !previous[offer.ID] {
+					added++
+				}
+			}
+			if added > 0 {
+				ui.Flash(fmt.Sprintf("%d new offers", added))
+			} else if ui.context.source != nil && ui.context.source.Unchanged {
+				ui.SetStatus(ui.context.source.String())
+			}
+		})
+	}()
+}
+
+// Flash tells something important to the user by presenting a message in
+// the status bar with a different color for a few seconds.
+func (ui *UserInterface) Flash(message string) {
+	ui.SetStatus(message)
+	ui.statusWidget.SetBackgroundColor(tcell.ColorRed)
+	time.AfterFunc(3*time.Second, func() {
+		ui.application.QueueUpdateDraw(func() {
+			ui.statusWidget.SetBackgroundColor(tcell.ColorGreen)
+		})
+	})
+}
+
+// showError presents the error dialog on top of the current page, telling
+// that the offers for the given locations could not be retrieved.
+func (ui *UserInterface) showError(locations []Location, err error) {
+	ui.errorDialog.SetError(err)
+	ui.errorDialog.SetActionFunc(func(action int) {
+		ui.pagesWidget.HidePage("error")
+		ui.SwitchToLocations()
+		switch action {
+		case ErrorActionRetry:
+			ui.loadLocations(locations, ui.context.offline)
+		case ErrorActionUseCache:
+			ui.loadLocations(locations, true)
+		}
+	})
+	ui.pagesWidget.ShowPage("error")
+	ui.application.SetFocus(ui.errorDialog)
+	ui.SetStatus("Left/Right:SelectAction   Enter:Confirm   Esc:Back")
+}
+
+// toggleRead switches the read state of the offer selected in the list.
+func (ui *UserInterface) toggleRead() {
+	id := ui.jobOffersList.GetSelectedOfferID()
+	if ui.context.GetOffer(id) == nil {
+		return
+	}
+	if err := ui.context.SetRead(id, !ui.context.IsRead(id)); err != nil {
+		ui.SetStatus(err.Error())
+	}
+	ui.jobOffersList.Refresh()
+}
+
+// offerFlags tells how an offer should be presented in the lists.
+func (ui *UserInterface) offerFlags(offer Offer) OfferFlags {
+	return OfferFlags{
+		Unread:  !ui.context.IsRead(offer.ID),
+		Starred: ui.context.IsStarred(offer.ID),
+		New:     ui.context.IsNew(offer.ID),
+		Hidden:  ui.context.IsHidden(offer),
+	}
+}
+
+// toggleStarred stars or unstars the given offer.
+func (ui *UserInterface) toggleStarred(offer Offer) {
+	starred := !ui.context.IsStarred(offer.ID)
+	if err := ui.context.SetStarred(offer, starred); err != nil {
+		ui.SetStatus(err.Error())
+	} else if starred {
+		ui.SetStatus("Offer starred")
+	} else {
+		ui.SetStatus("Offer unstarred")
+	}
+	ui.jobOffersList.Refresh()
+}
+
+// toggleHidden hides the given offer, or shows it again if it was hidden.
+func (ui *UserInterface) toggleHidden(offer Offer) {
+	hidden := !ui.context.IsHidden(offer)
+	if err := ui.context.SetHidden(offer.ID, hidden); err != nil {
+		ui.SetStatus(err.Error())
+	} else if hidden {
+		ui.SetStatus("Offer hidden, press H in the list to show hidden offers")
+	} else if ui.context.IsHidden(offer) {
+		ui.SetStatus("The company of this offer is hidden, press D to show it")
+	} else {
+		ui.SetStatus("Offer shown")
+	}
+	ui.jobOffersList.Refresh()
+}
+
+// toggleHiddenCompany shows again the offers of the company of the given
+// offer if they were hidden. Otherwise, it asks for the rule used to hide
+// them, which is the name of the company or a regular expression.
+func (ui *UserInterface) toggleHiddenCompany(offer Offer) {
+	company := strings.TrimSpace(offer.Company)
+	removed, err := ui.context.UnhideCompany(company)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	if removed > 0 {
+		ui.SetStatus(fmt.Sprintf("Offers from %s are shown again", company))
+		ui.jobOffersList.Refresh()
+		return
+	}
+	ui.Prompt("Hide company (or /regexp/): ", company, nil, func(rule string, accepted bool) {
+		if !accepted {
+			ui.SetStatus("Nothing hidden")
+			return
+		}
+		if err := ui.context.HideCompany(rule); err != nil {
+			ui.SetStatus(err.Error())
+			return
+		}
+		ui.SetStatus(fmt.Sprintf("Offers from %s are hidden", strings.TrimSpace(rule)))
+		ui.jobOffersList.Refresh()
+	})
+}
+
+// copyToClipboard puts the text in the clipboard of the terminal, telling
+// what was copied in the status bar.
+func (ui *UserInterface) copyToClipboard(what, text string) {
+	if err := CopyToClipboard(os.Stdout, text); err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	ui.SetStatus(what + " copied to clipboard")
+}
+
+// changeSort sorts the given list by the next field, or reverses the order
+// if reverse is set.
+func (ui *UserInterface) changeSort(list *OfferList, reverse bool) {
+	by, descending := list.GetSort()
+	if reverse {
+		descending = !descending
+	} else {
+		by = by.Next()
+	}
+	list.SetSort(by, descending)
+	order := "ascending"
+	if descending {
+		order = "descending"
+	}
+	ui.SetStatus(fmt.Sprintf("Sorted by %s, %s", by, order))
+}
+
+// promptExport asks for the path of the file to export the given offers to.
+// The format is guessed from the extension of the file.
+func (ui *UserInterface) promptExport(offers []Offer) {
+	ui.Prompt("Export to (.json, .ndjson, .csv, .md): ", "", nil, func(path string, accepted bool) {
+		path = strings.TrimSpace(path)
+		if !accepted || path == "" {
+			ui.SetStatus("Export cancelled")
+			return
+		}
+		if err := ExportFile(path, FormatFromPath(path), offers); err != nil {
+			ui.SetStatus(err.Error())
+			return
+		}
+		ui.SetStatus(fmt.Sprintf("Exported %d offers to %s", len(offers), path))
+	})
+}
+
 func (ui *UserInterface) SwitchToLocations() {
+	ui.locationsList.SetNewCounts(ui.context.NewOfferCount)
 	ui.pagesWidget.SwitchToPage("locations")
 	ui.application.SetFocus(ui.locationsList)
 	ui.SetTitle("JobFluCli | Select a location")
-	ui.SetStatus("q:Quit   j/Up:MoveUp   k/Down:MoveDown")
+	ui.SetStatus("q:Quit   j/Up:MoveUp   k/Down:MoveDown   Space:Mark   Enter:Open   S:SavedOffers")
+}
+
+func (ui *UserInterface) SwitchToSaved() {
+	ui.savedList.SetOfferList(ui.context.StarredOffers())
+	ui.pagesWidget.SwitchToPage("saved")
+	ui.application.SetFocus(ui.savedList)
+	ui.SetTitle(fmt.Sprintf("JobFluCli | Saved offers (%d)", len(ui.context.StarredOffers())))
+	ui.SetStatus("q:Back   j/Up:MoveUp   k/Down:MoveDown   s:Unstar   o/O:Sort")
 }
 
 func (ui *UserInterface) SwitchToList() {
 	ui.pagesWidget.SwitchToPage("list")
+	ui.jobOffersList.SetHighlight(ui.context.searchQuery)
+	if len(ui.context.locations) > 1 {
+		ui.jobOffersList.SetLocationFunc(func(offer Offer) string {
+			return strings.Join(ui.context.OfferLocationNames(offer.ID), ", ")
+		})
+	} else {
+		ui.jobOffersList.SetLocationFunc(nil)
+	}
 	ui.jobOffersList.SetOfferList(ui.context.offers)
 	ui.application.SetFocus(ui.jobOffersList)
-	ui.SetTitle("JobFluCli | List of offers")
-	ui.SetStatus("q:Quit   j/Up:MoveUp   k/Down:MoveDown   l:SwitchLocation")
+	title := "JobFluCli | Offers in " + strings.Join(ui.context.LocationNames(), ", ")
+	if ui.context.tagFilter != nil {
+		title += fmt.Sprintf(" | Tags: %s", ui.context.tagFilter)
+	}
+	if ui.context.searchQuery != "" {
+		title += fmt.Sprintf(" | Search: %s", ui.context.searchQuery)
+	}
+	if ui.context.preset != nil {
+		title += fmt.Sprintf(" | Preset: %s", ui.context.preset.Name)
+	}
+	if ui.context.query != nil {
+		title += fmt.Sprintf(" | Filter: %s", ui.context.query)
+	}
+	if ui.context.showHidden {
+		title += " | Showing hidden"
+	}
+	ui.SetTitle(title)
+	status := "q:Quit   j/Up:MoveUp   k/Down:MoveDown   l:SwitchLocation   t:Tags   p:Presets   /:Search   f:Filter   c:ClearFilter   e:Export   N:ToggleRead   Tab:NextUnread   s:Star   o/O:Sort   g:Group   Space:Expand   d/D:HideOffer/Company   H:ShowHidden"
+	if source := ui.context.source.String(); source != "" {
+		status += "   [" + source + "]"
+	}
+	ui.SetStatus(status)
+}
+
+func (ui *UserInterface) SwitchToPresets() {
+	ui.presetsList.SetPresets(config.Presets, ui.context.preset)
+	ui.pagesWidget.SwitchToPage("presets")
+	ui.application.SetFocus(ui.presetsList)
+	ui.SetTitle("JobFluCli | Select a filter preset")
+	ui.SetStatus("q:Back   j/Up:MoveUp   k/Down:MoveDown   Enter:Apply")
+}
+
+func (ui *UserInterface) SwitchToTags() {
+	ui.tagsList.SetTags(ui.context.Tags(), ui.context.tagFilter)
+	ui.pagesWidget.SwitchToPage("tags")
+	ui.application.SetFocus(ui.tagsList)
+	ui.setTagsTitle()
+	ui.SetStatus("q:Back   Space:Mark   a:ToggleAndOr   Enter:Apply")
+}
+
+// setTagsTitle updates the title bar with the way marked tags are combined.
+func (ui *UserInterface) setTagsTitle() {
+	mode := "any marked tag (OR)"
+	if ui.tagsList.MatchAll() {
+		mode = "every marked tag (AND)"
+	}
+	ui.SetTitle("JobFluCli | Select tags | Match " + mode)
 }
 
 func (ui *UserInterface) SwitchToOffer(o *Offer) {
+	ui.offerReturnPage, _ = ui.pagesWidget.GetFrontPage()
+	readErr := ui.context.SetRead(o.ID, true)
+	ui.jobOfferDetail.SetHighlight(ui.context.searchQuery)
 	ui.jobOfferDetail.SetOffer(o)
 	ui.pagesWidget.SwitchToPage("detail")
 	ui.application.SetFocus(ui.jobOfferDetail)
-	ui.SetTitle("JobFluCli | Offer Information")
-	ui.SetStatus("q:Back   j/Up:MoveUp   k/Down:MoveDown")
+	ui.setOfferTitle()
+	status := "q:Back   j/Up:MoveUp   k/Down:MoveDown   s:Star   e:Export   d/D:HideOffer/Company   o:OpenURL   y/Y:CopyURL/Description"
+	if ui.context.searchQuery != "" {
+		status += "   n/N:NextMatch/PrevMatch"
+	}
+	if readErr != nil {
+		status = readErr.Error()
+	}
+	ui.SetStatus(status)
+}
+
+// setOfferTitle updates the title bar telling whether the offer presented is
+// starred.
+func (ui *UserInterface) setOfferTitle() {
+	title := "JobFluCli | Offer Information"
+	if ui.context.IsStarred(ui.jobOfferDetail.offer.ID) {
+		title += " | Starred"
+	}
+	ui.SetTitle(title)
 }
 
 // Run executes the graphical view for this application
@@ -160,5 +827,8 @@ func (ui *UserInterface) Run() error {
 	ui.application.SetInputCapture(ui.globalApplicationKeybidings)
 	ui.application.SetRoot(ui.layout, true)
 	ui.application.SetFocus(ui.pagesWidget)
+	if interval := config.RefreshInterval.Duration; interval > 0 {
+		go ui.autoRefresh(interval)
+	}
 	return ui.application.Run()
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// The actions that can be taken from an error dialog.
+const (
+	ErrorActionBack = iota
+	ErrorActionRetry
+	ErrorActionUseCache
+)
+
+// ErrorDialog is a modal widget used to tell the user that the offers could
+// not be retrieved, offering some ways to recover from the failure.
+type ErrorDialog struct {
+	*tview.Modal
+}
+
+// NewErrorDialog builds a modal widget that presents an error message and
+// the buttons to retry the operation, to use the cached offers, or to go
+// back to the previous page.
+func NewErrorDialog() *ErrorDialog {
+	dialog := &ErrorDialog{Modal: tview.NewModal()}
+	dialog.AddButtons([]string{"Retry", "Use cache", "Back"})
+	dialog.SetBackgroundColor(tcell.ColorMaroon)
+	dialog.SetTextColor(tcell.ColorWhite)
+	return dialog
+}
+
+// SetError updates the message presented in the dialog.
+func (ed *ErrorDialog) SetError(err error) {
+	ed.SetText("Cannot retrieve the offers.\n\n" + err.Error())
+	ed.SetFocus(0)
+}
+
+// SetActionFunc sets the function to call when the user picks an action.
+// Dismissing the dialog with Esc counts as going back.
+func (ed *ErrorDialog) SetActionFunc(handler func(action int)) {
+	ed.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		switch buttonIndex {
+		case 0:
+			handler(ErrorActionRetry)
+		case 1:
+			handler(ErrorActionUseCache)
+		default:
+			handler(ErrorActionBack)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
 )
 
@@ -11,32 +13,94 @@ type LocationsTable struct {
 
 	// This map indicates which location is present at each row of the table.
 	rowLocationIndex map[int]Location
+
+	// The locations marked by the user to be fetched together.
+	selected map[Location]bool
 }
 
 // NewLocationsTable builds a table widget that can be used to display a list
 // of locations where offers are available through the feed. Then, it's
-// possible to view offers for that particular location.
+// possible to view offers for that particular location. Several locations
+// can be marked to view their offers together. When there are several
+// providers, their locations are grouped under the name of the provider.
 func NewLocationsTable() *LocationsTable {
 	table := &LocationsTable{
 		Table:            tview.NewTable(),
 		rowLocationIndex: make(map[int]Location),
+		selected:         make(map[Location]bool),
 	}
 
 	// Populate the table with locations.
 	nextRow := 0
-	for key, location := range Locations {
-		cell := tview.NewTableCell(location.title)
+	var provider Provider
+	for _, key := range SortedLocations() {
+		if len(Providers) > 1 && Locations[key].provider != provider {
+			provider = Locations[key].provider
+			for column, text := range []string{"", provider.Name(), ""} {
+				header := tview.NewTableCell(text)
+				header.SetAttributes(tcell.AttrBold)
+				header.SetSelectable(false)
+				table.SetCell(nextRow, column, header)
+			}
+			nextRow++
+		}
+		cell := tview.NewTableCell(Locations[key].title)
 		cell.SetExpansion(1)
-		table.SetCell(nextRow, 0, cell)
+		table.SetCell(nextRow, 1, cell)
 		table.rowLocationIndex[nextRow] = key
+		table.renderMark(nextRow)
 		nextRow++
 	}
 
 	table.SetSelectable(true, false)
+	for row := 0; row < nextRow; row++ {
+		// Skip the name of the first provider.
+		if _, ok := table.rowLocationIndex[row]; ok {
+			table.Select(row, 0)
+			break
+		}
+	}
 
 	return table
 }
 
+// SetNewCounts presents next to each location how many offers were new
+// in the latest fetch, as told by the given function.
+func (lt *LocationsTable) SetNewCounts(count func(location Location) int) {
+	for row, location := range lt.rowLocationIndex {
+		text := ""
+		if n := count(location); n > 0 {
+			text = fmt.Sprintf("%d new", n)
+		}
+		countCell := tview.NewTableCell(text)
+		countCell.SetTextColor(tcell.ColorYellow)
+		countCell.SetAlign(tview.AlignRight)
+		lt.SetCell(row, 2, countCell)
+	}
+}
+
+// renderMark updates the flag column of the given row.
+func (lt *LocationsTable) renderMark(row int) {
+	mark := " "
+	if lt.selected[lt.rowLocationIndex[row]] {
+		mark = "*"
+	}
+	markCell := tview.NewTableCell(mark)
+	markCell.SetTextColor(tcell.ColorYellow)
+	lt.SetCell(row, 0, markCell)
+}
+
+// ToggleSelected marks or unmarks the location in the selected row.
+func (lt *LocationsTable) ToggleSelected() {
+	row, _ := lt.GetSelection()
+	location, ok := lt.rowLocationIndex[row]
+	if !ok {
+		return
+	}
+	lt.selected[location] = !lt.selected[location]
+	lt.renderMark(row)
+}
+
 // GetSelectedLocation converts the selected index of the table into the
 // valid location constant that can be used by the context to fetch new
 // job offers.
@@ -44,3 +108,19 @@ func (lt *LocationsTable) GetSelectedLocation() Location {
 	selectedRow, _ := lt.GetSelection()
 	return lt.rowLocationIndex[selectedRow]
 }
+
+// GetSelectedLocations returns the locations marked by the user, in the
+// order they are presented. If no location is marked, the location in the
+// selected row is returned.
+func (lt *LocationsTable) GetSelectedLocations() []Location {
+	var locations []Location
+	for row := 0; row < lt.GetRowCount(); row++ {
+		if location, ok := lt.rowLocationIndex[row]; ok && lt.selected[location] {
+			locations = append(locations, location)
+		}
+	}
+	if len(locations) == 0 {
+		locations = append(locations, lt.GetSelectedLocation())
+	}
+	return locations
+}
@@ -1,17 +1,140 @@
 package main
 
 import (
+	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
+	"sort"
 	"strings"
 )
 
 // OfferList is a widget that represents the list of offers downloaded.
 type OfferList struct {
 	*tview.Table
-	backingOffers   []Offer                // the offers themselves.
-	backingOfferIds map[int]int            // maps each row with the underlying offer
-	filterFunc      func(offer Offer) bool // used to filter the presented offers
+	backingOffers   []Offer                      // the offers themselves.
+	backingOfferIds map[int]int                  // maps each row with the underlying offer
+	filterFunc      func(offer Offer) bool       // used to filter the presented offers
+	highlight       string                       // text to highlight in the rows
+	locationFunc    func(offer Offer) string     // names the locations of an offer
+	flagsFunc       func(offer Offer) OfferFlags // tells how to present an offer
+	sortBy          OfferSort                    // the field the offers are sorted by
+	sortDescending  bool                         // whether the sort order is reversed
+	grouped         bool                         // whether offers are grouped by company
+	expanded        map[string]bool              // the groups that present their offers
+	backingGroups   map[int]string               // maps each group row with its company
+}
+
+// OfferSort tells which field is used to sort the offers of a list.
+type OfferSort int
+
+// The fields offers can be sorted by. SortByFeed keeps the order in which
+// the offers were retrieved.
+const (
+	SortByFeed OfferSort = iota
+	SortByDate
+	SortByCompany
+	SortByPosition
+	SortByTags
+	sortFieldCount
+)
+
+// String names the field used to sort.
+func (by OfferSort) String() string {
+	switch by {
+	case SortByDate:
+		return "date"
+	case SortByCompany:
+		return "company"
+	case SortByPosition:
+		return "position"
+	case SortByTags:
+		return "tags"
+	default:
+		return "feed"
+	}
+}
+
+// Next returns the field that follows this one, going back to SortByFeed
+// after the last one.
+func (by OfferSort) Next() OfferSort {
+	return (by + 1) % sortFieldCount
+}
+
+// sortOffers returns a copy of the offers sorted by the given field. Offers
+// that are equal by that field keep the order they had.
+func sortOffers(offers []Offer, by OfferSort, descending bool) []Offer {
+	sorted := make([]Offer, len(offers))
+	copy(sorted, offers)
+	if by == SortByFeed {
+		if descending {
+			for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+				sorted[i], sorted[j] = sorted[j], sorted[i]
+			}
+		}
+		return sorted
+	}
+
+	compare := func(a, b Offer) int {
+		switch by {
+		case SortByDate:
+			switch {
+			case a.CreationDate.Before(b.CreationDate):
+				return -1
+			case a.CreationDate.After(b.CreationDate):
+				return 1
+			}
+			return 0
+		case SortByCompany:
+			return strings.Compare(sortKey(a.Company), sortKey(b.Company))
+		case SortByPosition:
+			return strings.Compare(sortKey(a.Position), sortKey(b.Position))
+		default:
+			return len(a.Tags) - len(b.Tags)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if descending {
+			return compare(sorted[i], sorted[j]) > 0
+		}
+		return compare(sorted[i], sorted[j]) < 0
+	})
+	return sorted
+}
+
+// sortKey normalises a text so that it can be compared when sorting.
+func sortKey(text string) string {
+	return strings.ToLower(strings.TrimSpace(text))
+}
+
+// OfferFlags describes the state of an offer, so that the list can present
+// it in a different way.
+type OfferFlags struct {
+	// The offer has not been opened yet.
+	Unread bool
+	// The offer was starred by the user.
+	Starred bool
+	// The offer was not present in the previous fetch.
+	New bool
+	// The offer was hidden by the user, but hidden offers are being shown.
+	Hidden bool
+}
+
+// String summarises the flags using a letter for each flag that is set.
+func (flags OfferFlags) String() string {
+	summary := []byte("    ")
+	if flags.Unread {
+		summary[0] = 'N'
+	}
+	if flags.Starred {
+		summary[1] = '!'
+	}
+	if flags.New {
+		summary[2] = '+'
+	}
+	if flags.Hidden {
+		summary[3] = 'x'
+	}
+	return string(summary)
 }
 
 // NewOfferList returns a new offerlist widget that can be used to present offers.
@@ -20,6 +143,7 @@ func NewOfferList() *OfferList {
 	offerList.Table = tview.NewTable()
 	offerList.SetSelectable(true, false)
 	offerList.filterFunc = nil
+	offerList.expanded = make(map[string]bool)
 	return offerList
 }
 
@@ -31,6 +155,134 @@ func (ol *OfferList) SetFilterFunc(filter func(offer Offer) bool) {
 	ol.reloadTable()
 }
 
+// SetLocationFunc adds a column to the table with the locations each offer
+// was published in, as described by the given function. A nil function
+// removes the column.
+// This function modifies the widget. Therefore, it is required to either
+// call this in the mainthread, or dispatch an event to the application.
+func (ol *OfferList) SetLocationFunc(locations func(offer Offer) string) {
+	ol.locationFunc = locations
+	ol.reloadTable()
+}
+
+// SetFlagsFunc adds a column to the table with the flags of each offer, as
+// given by the function. Unread offers are presented in bold, and hidden
+// offers are grayed out. A nil function removes the column.
+// This function modifies the widget. Therefore, it is required to either
+// call this in the mainthread, or dispatch an event to the application.
+func (ol *OfferList) SetFlagsFunc(flags func(offer Offer) OfferFlags) {
+	ol.flagsFunc = flags
+	ol.reloadTable()
+}
+
+// Refresh renders again every row of the table, so that changes in the
+// state of the offers are presented.
+// This function modifies the widget. Therefore, it is required to either
+// call this in the mainthread, or dispatch an event to the application.
+func (ol *OfferList) Refresh() {
+	ol.reloadTable()
+}
+
+// GetSelectedOfferID returns the ID of the offer in the selected row, or 0
+// if the table is empty or a company row is selected.
+func (ol *OfferList) GetSelectedOfferID() int {
+	row, _ := ol.GetSelection()
+	return ol.backingOfferIds[row]
+}
+
+// SelectOffer moves the selection to the row of the offer with the given ID.
+// It returns false if the offer is not presented in the table.
+func (ol *OfferList) SelectOffer(id int) bool {
+	for row, rowID := range ol.backingOfferIds {
+		if rowID == id {
+			ol.Select(row, 0)
+			return true
+		}
+	}
+	return false
+}
+
+// SelectNextOffer moves the selection to the next row after the selected
+// one whose offer ID satisfies the given function, wrapping around at the
+// end of the table. It returns false if no row satisfies the function.
+func (ol *OfferList) SelectNextOffer(match func(id int) bool) bool {
+	rows := ol.GetRowCount()
+	selected, _ := ol.GetSelection()
+	for i := 1; i <= rows; i++ {
+		row := (selected + i) % rows
+		if id, ok := ol.backingOfferIds[row]; ok && match(id) {
+			ol.Select(row, 0)
+			return true
+		}
+	}
+	return false
+}
+
+// SetSort sorts the offers presented in the table by the given field. The
+// selection is kept on the same offer.
+// This function modifies the widget. Therefore, it is required to either
+// call this in the mainthread, or dispatch an event to the application.
+func (ol *OfferList) SetSort(by OfferSort, descending bool) {
+	ol.sortBy = by
+	ol.sortDescending = descending
+	ol.reloadTable()
+}
+
+// GetSort returns the field the offers are sorted by, and whether the order
+// is reversed.
+func (ol *OfferList) GetSort() (OfferSort, bool) {
+	return ol.sortBy, ol.sortDescending
+}
+
+// SetGrouped switches between presenting the offers in a flat list and
+// grouping them under a row for each company. Groups start collapsed, and
+// companies with a single offer are not grouped.
+// This function modifies the widget. Therefore, it is required to either
+// call this in the mainthread, or dispatch an event to the application.
+func (ol *OfferList) SetGrouped(grouped bool) {
+	ol.grouped = grouped
+	ol.reloadTable()
+}
+
+// IsGrouped tells whether the offers are grouped by company.
+func (ol *OfferList) IsGrouped() bool {
+	return ol.grouped
+}
+
+// ToggleSelectedGroup expands or collapses the group the selected row
+// belongs to, which is either the company row or one of its offers. When a
+// group is collapsed, its company row is selected. It returns false if the
+// selected row does not belong to a group.
+// This function modifies the widget. Therefore, it is required to either
+// call this in the mainthread, or dispatch an event to the application.
+func (ol *OfferList) ToggleSelectedGroup() bool {
+	row, _ := ol.GetSelection()
+	group, ok := ol.backingGroups[row]
+	if !ok {
+		return false
+	}
+	ol.expanded[group] = !ol.expanded[group]
+	if !ol.expanded[group] {
+		// The selected offer is about to be hidden.
+		for groupRow, rowGroup := range ol.backingGroups {
+			if rowGroup == group && ol.backingOfferIds[groupRow] == 0 {
+				ol.Select(groupRow, 0)
+			}
+		}
+	}
+	ol.reloadTable()
+	return true
+}
+
+// SetHighlight paints the occurrences of the given text in the rows of the
+// table. An empty string removes the highlight.
+// This function modifies the widget. Therefore, it is required to either
+// call this in the mainthread, or dispatch an event to the application.
+func (ol *OfferList) SetHighlight(text string) {
+	ol.highlight = text
+	ol.reloadTable()
+}
+
 // SetOfferList updates the list of offers presented to the users.
 // This function modifies the widget. Therefore, it is required to either
 // call this in the mainthread, or dispatch an event to the application.
@@ -39,41 +291,234 @@ func (ol *OfferList) SetOfferList(offers []Offer) {
 	ol.reloadTable()
 }
 
+// renderHeader puts the names of the columns in the first row of the table,
+// marking the column the offers are sorted by.
+func (ol *OfferList) renderHeader() {
+	type column struct {
+		title string
+		sort  OfferSort
+	}
+	var columns []column
+	if ol.flagsFunc != nil {
+		columns = append(columns, column{"", SortByFeed})
+	}
+	columns = append(columns, column{"Date", SortByDate})
+	if ol.locationFunc != nil {
+		columns = append(columns, column{"Location", SortByFeed})
+	}
+	columns = append(columns, column{"Company", SortByCompany}, column{"Position", SortByPosition}, column{"Tags", SortByTags})
+
+	for i, col := range columns {
+		title := col.title
+		if ol.sortBy != SortByFeed && col.sort == ol.sortBy {
+			if ol.sortDescending {
+				title += " v"
+			} else {
+				title += " ^"
+			}
+		}
+		cell := tview.NewTableCell(title)
+		cell.SetAttributes(tcell.AttrBold | tcell.AttrUnderline)
+		cell.SetSelectable(false)
+		ol.SetCell(0, i, cell)
+	}
+}
+
 // SetOfferList will update the table contained in the offer list by the list
 // of offers given as an argument. It will also update every handler so that
-// the new items of the table can be selected to toggle them.
+// the new items of the table can be selected to toggle them. The selection
+// is kept on the same offer if it is still presented.
 func (ol *OfferList) reloadTable() {
+	selectedID := ol.GetSelectedOfferID()
+	selectedRow, _ := ol.GetSelection()
+	selectedGroup, groupSelected := ol.backingGroups[selectedRow]
+
 	// Clear the selection model.
 	ol.Clear()
 	ol.backingOfferIds = make(map[int]int)
+	ol.backingGroups = make(map[int]string)
+	ol.renderHeader()
+	ol.SetFixed(1, 0)
+
+	var visible []Offer
+	for _, offer := range sortOffers(ol.backingOffers, ol.sortBy, ol.sortDescending) {
+		if ol.filterFunc == nil || ol.filterFunc(offer) {
+			visible = append(visible, offer)
+		}
+	}
 
 	// Put the new selection model.
-	nextRow := 0
-	for _, offer := range ol.backingOffers {
-		if ol.filterFunc != nil && !ol.filterFunc(offer) {
-			continue
-		}
-
-		// Format timestamp
-		timestamp := offer.CreationDate.Format("2006 Jan 2, 15:04")
-		timestampCell := tview.NewTableCell(timestamp)
-		timestampCell.SetTextColor(tcell.ColorTurquoise)
-		ol.SetCell(nextRow, 0, timestampCell)
-
-		// Format the company
-		company := strings.TrimSpace(offer.Company)
-		companyCell := tview.NewTableCell(company)
-		companyCell.SetTextColor(tcell.ColorGreen)
-		ol.SetCell(nextRow, 1, companyCell)
-
-		// Format the position
-		position := strings.TrimSpace(offer.Position)
-		positionCell := tview.NewTableCell(position)
-		positionCell.SetExpansion(1)
-		ol.SetCell(nextRow, 2, positionCell)
-
-		// Put a backing ID so that we can refer to this offer later.
-		ol.backingOfferIds[nextRow] = offer.ID
-		nextRow++
+	nextRow := 1
+	collapsedRow := 0
+	if !ol.grouped {
+		for _, offer := range visible {
+			ol.renderOffer(nextRow, offer, false)
+			nextRow++
+		}
+	} else {
+		// Groups are presented in the order of their first offer.
+		var companies []string
+		groups := make(map[string][]Offer)
+		for _, offer := range visible {
+			company := sortKey(offer.Company)
+			if _, ok := groups[company]; !ok {
+				companies = append(companies, company)
+			}
+			groups[company] = append(groups[company], offer)
+		}
+		for _, company := range companies {
+			group := groups[company]
+			if len(group) == 1 {
+				ol.renderOffer(nextRow, group[0], false)
+				nextRow++
+				continue
+			}
+			ol.renderGroup(nextRow, company, group)
+			if groupSelected && selectedGroup == company && selectedID == 0 {
+				// Keep the selection on the company row.
+				selectedRow = nextRow
+			}
+			nextRow++
+			if !ol.expanded[company] {
+				for _, offer := range group {
+					if offer.ID == selectedID {
+						// The selected offer is hidden in this group.
+						collapsedRow = nextRow - 1
+					}
+				}
+				continue
+			}
+			for _, offer := range group {
+				ol.renderOffer(nextRow, offer, true)
+				ol.backingGroups[nextRow] = company
+				nextRow++
+			}
+		}
+	}
+
+	// Keep the selection on the same offer, or on its company if the offer
+	// is in a collapsed group, or on the same row if the offer is gone,
+	// without landing on the header.
+	if selectedID == 0 || !ol.SelectOffer(selectedID) {
+		if collapsedRow > 0 {
+			selectedRow = collapsedRow
+		}
+		if selectedRow >= nextRow {
+			selectedRow = nextRow - 1
+		}
+		if selectedRow < 1 {
+			selectedRow = 1
+		}
+		ol.Select(selectedRow, 0)
+	}
+}
+
+// renderGroup puts in the given row the company of a group of offers,
+// telling how many offers are in the group and how many of them are unread.
+func (ol *OfferList) renderGroup(row int, company string, offers []Offer) {
+	unread := 0
+	for _, offer := range offers {
+		if ol.flagsFunc != nil && ol.flagsFunc(offer).Unread {
+			unread++
+		}
+	}
+	var cells []*tview.TableCell
+	if ol.flagsFunc != nil {
+		cells = append(cells, tview.NewTableCell(""))
+	}
+	cells = append(cells, tview.NewTableCell(""))
+	if ol.locationFunc != nil {
+		cells = append(cells, tview.NewTableCell(""))
+	}
+
+	marker := "+ "
+	if ol.expanded[company] {
+		marker = "- "
+	}
+	companyCell := tview.NewTableCell(marker + highlightMatches(strings.TrimSpace(offers[0].Company), ol.highlight))
+	companyCell.SetTextColor(tcell.ColorGreen)
+	cells = append(cells, companyCell)
+
+	summary := fmt.Sprintf("%d offers", len(offers))
+	if unread > 0 {
+		summary += fmt.Sprintf(", %d unread", unread)
+	}
+	countCell := tview.NewTableCell(summary)
+	countCell.SetExpansion(1)
+	countCell.SetTextColor(tcell.ColorGray)
+	cells = append(cells, countCell, tview.NewTableCell(""))
+
+	for column, cell := range cells {
+		if unread > 0 {
+			cell.SetAttributes(tcell.AttrBold)
+		}
+		ol.SetCell(row, column, cell)
+	}
+	ol.backingGroups[row] = company
+}
+
+// renderOffer puts the given offer in a row of the table. Offers inside an
+// expanded group are indented below the row of their company.
+func (ol *OfferList) renderOffer(row int, offer Offer, grouped bool) {
+	var flags OfferFlags
+	if ol.flagsFunc != nil {
+		flags = ol.flagsFunc(offer)
+	}
+	var cells []*tview.TableCell
+
+	// Format the flags
+	if ol.flagsFunc != nil {
+		flagsCell := tview.NewTableCell(flags.String())
+		flagsCell.SetTextColor(tcell.ColorRed)
+		cells = append(cells, flagsCell)
+	}
+
+	// Format timestamp
+	timestamp := offer.CreationDate.Format("2006 Jan 2, 15:04")
+	timestampCell := tview.NewTableCell(timestamp)
+	timestampCell.SetTextColor(tcell.ColorTurquoise)
+	cells = append(cells, timestampCell)
+
+	// Format the locations
+	if ol.locationFunc != nil {
+		locationCell := tview.NewTableCell(tview.Escape(ol.locationFunc(offer)))
+		locationCell.SetTextColor(tcell.ColorYellow)
+		cells = append(cells, locationCell)
+	}
+
+	// Format the company, which is already given by the group row
+	company := highlightMatches(strings.TrimSpace(offer.Company), ol.highlight)
+	if grouped {
+		company = "  `->"
 	}
+	companyCell := tview.NewTableCell(company)
+	companyCell.SetTextColor(tcell.ColorGreen)
+	cells = append(cells, companyCell)
+
+	// Format the position
+	position := strings.TrimSpace(offer.Position)
+	positionCell := tview.NewTableCell(highlightMatches(position, ol.highlight))
+	positionCell.SetExpansion(1)
+	if flags.New {
+		positionCell.SetTextColor(tcell.ColorYellow)
+	}
+	cells = append(cells, positionCell)
+
+	// Format the number of tags
+	tagsCell := tview.NewTableCell(fmt.Sprintf("%d", len(offer.Tags)))
+	tagsCell.SetAlign(tview.AlignRight)
+	cells = append(cells, tagsCell)
+
+	for column, cell := range cells {
+		if flags.Unread {
+			cell.SetAttributes(tcell.AttrBold)
+		}
+		if flags.Hidden {
+			cell.SetTextColor(tcell.ColorGray)
+		}
+		ol.SetCell(row, column, cell)
+	}
+
+	// Put a backing ID so that we can refer to this offer later.
+	ol.backingOfferIds[row] = offer.ID
 }
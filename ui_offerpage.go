@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/lunny/html2md"
 	"github.com/rivo/tview"
@@ -24,6 +25,9 @@ type OfferView struct {
 	tagsWidget        *tview.TableCell // tags in the offer
 	urlWidget         *tview.TableCell // link to open the offer in a browser
 	descriptionWidget *tview.TextView  // main content of the offer
+	highlight         string           // text to highlight in the description
+	matchCount        int              // occurrences of the highlighted text
+	currentMatch      int              // occurrence the description is scrolled to
 }
 
 // SetOffer will render the given offer in the widget. This function should be
@@ -37,8 +41,67 @@ func (ov *OfferView) SetOffer(offer *Offer) {
 	ov.dateWidget.SetText(offer.CreationDate.Format("Mon, 2 Jan 2006 15:04:05"))
 	ov.tagsWidget.SetText(strings.Join(offer.Tags, ", "))
 	ov.urlWidget.SetText(offer.URL)
-	ov.descriptionWidget.SetText(cleanContent(offer.Description))
+	ov.renderDescription()
+}
+
+// SetHighlight paints the occurrences of the given text in the description
+// of the offer. An empty string removes the highlight. Like SetOffer, this
+// function should be called in the main thread.
+func (ov *OfferView) SetHighlight(text string) {
+	ov.highlight = text
+	if ov.offer != nil {
+		ov.renderDescription()
+	}
+}
+
+// renderDescription fills the description widget with the content of the
+// offer, marking the occurrences of the highlighted text.
+func (ov *OfferView) renderDescription() {
+	content := cleanContent(ov.offer.Description)
+	text, count := markMatches(content, ov.highlight, true)
+	ov.matchCount = count
+	ov.currentMatch = 0
+	ov.descriptionWidget.SetText(text)
+	ov.descriptionWidget.Highlight()
 	ov.descriptionWidget.ScrollToBeginning()
+	if count > 0 {
+		ov.scrollToMatch()
+	}
+}
+
+// scrollToMatch highlights the current occurrence and scrolls to it.
+func (ov *OfferView) scrollToMatch() {
+	ov.descriptionWidget.Highlight(fmt.Sprintf("%d", ov.currentMatch))
+	ov.descriptionWidget.ScrollToHighlight()
+}
+
+// NextMatch scrolls the description to the next occurrence of the
+// highlighted text, wrapping around at the end.
+func (ov *OfferView) NextMatch() {
+	if ov.matchCount == 0 {
+		return
+	}
+	ov.currentMatch = (ov.currentMatch + 1) % ov.matchCount
+	ov.scrollToMatch()
+}
+
+// PrevMatch scrolls the description to the previous occurrence of the
+// highlighted text, wrapping around at the beginning.
+func (ov *OfferView) PrevMatch() {
+	if ov.matchCount == 0 {
+		return
+	}
+	ov.currentMatch = (ov.currentMatch + ov.matchCount - 1) % ov.matchCount
+	ov.scrollToMatch()
+}
+
+// MatchStatus describes the occurrence the description is scrolled to, or
+// returns an empty string if nothing is highlighted.
+func (ov *OfferView) MatchStatus() string {
+	if ov.matchCount == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Match %d of %d", ov.currentMatch+1, ov.matchCount)
 }
 
 // NewOfferView initialises a new widget to be used as a page to present
@@ -65,6 +128,7 @@ func NewOfferView() *OfferView {
 
 	// The description widget renders the offer content.
 	descriptionWidget := tview.NewTextView().SetWordWrap(true).SetScrollable(true)
+	descriptionWidget.SetDynamicColors(true).SetRegions(true)
 	offerView.descriptionWidget = descriptionWidget
 
 	// Reflow the stuff.
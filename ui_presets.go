@@ -0,0 +1,73 @@
+package main
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// PresetsTable is a table widget used to pick a filter preset.
+type PresetsTable struct {
+	// The backing table that presents the list of presets.
+	*tview.Table
+
+	// This map indicates which preset is present at each row of the table.
+	// The first row is used to remove the preset, so it has no preset.
+	rowPresetIndex map[int]*FilterPreset
+}
+
+// NewPresetsTable builds a table widget that can be used to display the
+// filter presets read from the config file.
+func NewPresetsTable() *PresetsTable {
+	table := &PresetsTable{
+		Table:          tview.NewTable(),
+		rowPresetIndex: make(map[int]*FilterPreset),
+	}
+	table.SetSelectable(true, false)
+	return table
+}
+
+// SetPresets updates the list of presets presented to the user. The active
+// preset is flagged and selected.
+func (pt *PresetsTable) SetPresets(presets []FilterPreset, active *FilterPreset) {
+	pt.Clear()
+	pt.rowPresetIndex = make(map[int]*FilterPreset)
+
+	pt.renderRow(0, "(none)", "present every offer", active == nil)
+	pt.Select(0, 0)
+	for i := range presets {
+		preset := &presets[i]
+		row := i + 1
+		pt.rowPresetIndex[row] = preset
+		isActive := active != nil && active.Name == preset.Name
+		pt.renderRow(row, preset.Name, preset.String(), isActive)
+		if isActive {
+			pt.Select(row, 0)
+		}
+	}
+}
+
+// renderRow fills a row of the table with the given preset information.
+func (pt *PresetsTable) renderRow(row int, name, description string, active bool) {
+	mark := " "
+	if active {
+		mark = "*"
+	}
+	markCell := tview.NewTableCell(mark)
+	markCell.SetTextColor(tcell.ColorYellow)
+	pt.SetCell(row, 0, markCell)
+
+	nameCell := tview.NewTableCell(tview.Escape(name))
+	nameCell.SetTextColor(tcell.ColorGreen)
+	pt.SetCell(row, 1, nameCell)
+
+	descriptionCell := tview.NewTableCell(tview.Escape(description))
+	descriptionCell.SetExpansion(1)
+	pt.SetCell(row, 2, descriptionCell)
+}
+
+// GetSelectedPreset returns the preset in the selected row, or nil if the
+// user chose to remove the preset.
+func (pt *PresetsTable) GetSelectedPreset() *FilterPreset {
+	row, _ := pt.GetSelection()
+	return pt.rowPresetIndex[row]
+}
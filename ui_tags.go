@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// TagsTable is a table widget used to pick the tags to filter offers by.
+type TagsTable struct {
+	// The backing table that presents the list of tags.
+	*tview.Table
+
+	rowTagIndex map[int]string  // which tag is present at each row
+	selected    map[string]bool // tags marked by the user
+	matchAll    bool            // whether to combine the tags using AND
+}
+
+// NewTagsTable builds a table widget that can be used to display the tags
+// available in the current list of offers. Multiple tags can be marked, and
+// then turned into a filter for the offer list.
+func NewTagsTable() *TagsTable {
+	table := &TagsTable{
+		Table:       tview.NewTable(),
+		rowTagIndex: make(map[int]string),
+		selected:    make(map[string]bool),
+	}
+	table.SetSelectable(true, false)
+	return table
+}
+
+// SetTags updates the list of tags presented to the user. If a filter is
+// given, the tags in the filter will be marked as selected.
+func (tt *TagsTable) SetTags(tags []TagCount, filter *TagFilter) {
+	tt.Clear()
+	tt.rowTagIndex = make(map[int]string)
+	tt.selected = make(map[string]bool)
+	tt.matchAll = false
+	if filter != nil {
+		for _, tag := range filter.Tags {
+			tt.selected[tag] = true
+		}
+		tt.matchAll = filter.MatchAll
+	}
+
+	for row, tag := range tags {
+		tt.rowTagIndex[row] = tag.Tag
+
+		tagCell := tview.NewTableCell(tag.Tag)
+		tagCell.SetExpansion(1)
+		tt.SetCell(row, 1, tagCell)
+
+		countCell := tview.NewTableCell(fmt.Sprintf("%d", tag.Count))
+		countCell.SetTextColor(tcell.ColorTurquoise)
+		countCell.SetAlign(tview.AlignRight)
+		tt.SetCell(row, 2, countCell)
+
+		tt.renderMark(row)
+	}
+	tt.Select(0, 0)
+	tt.ScrollToBeginning()
+}
+
+// renderMark updates the flag column of the given row.
+func (tt *TagsTable) renderMark(row int) {
+	mark := " "
+	if tt.selected[tt.rowTagIndex[row]] {
+		mark = "*"
+	}
+	markCell := tview.NewTableCell(mark)
+	markCell.SetTextColor(tcell.ColorYellow)
+	tt.SetCell(row, 0, markCell)
+}
+
+// ToggleSelected marks or unmarks the tag in the selected row.
+func (tt *TagsTable) ToggleSelected() {
+	row, _ := tt.GetSelection()
+	tag, ok := tt.rowTagIndex[row]
+	if !ok {
+		return
+	}
+	tt.selected[tag] = !tt.selected[tag]
+	tt.renderMark(row)
+}
+
+// ToggleMatchAll switches between requiring every marked tag (AND) or any
+// of the marked tags (OR).
+func (tt *TagsTable) ToggleMatchAll() {
+	tt.matchAll = !tt.matchAll
+}
+
+// MatchAll returns whether the marked tags will be combined using AND.
+func (tt *TagsTable) MatchAll() bool {
+	return tt.matchAll
+}
+
+// GetFilter converts the marked tags into a filter that can be applied to
+// the context. If no tag is marked, nil is returned.
+func (tt *TagsTable) GetFilter() *TagFilter {
+	filter := &TagFilter{MatchAll: tt.matchAll}
+	for row := 0; row < len(tt.rowTagIndex); row++ {
+		if tag := tt.rowTagIndex[row]; tt.selected[tag] {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+	if len(filter.Tags) == 0 {
+		return nil
+	}
+	return filter
+}
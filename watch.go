@@ -0,0 +1,123 @@
+package main
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runWatch polls the feeds of some locations every so often, and runs a
+// command for every new offer that passes the filters. The offers found in
+// each poll are stored, so that offers are not reported twice even if the
+// command is restarted. The first poll of a location only records the
+// offers already present in the feed.
+func runWatch(args []string, offline bool) error {
+	flags := newFlagSet("watch", &offline)
+	filters := addFilterFlags(flags)
+	interval := flags.Duration("interval", 30*time.Minute, "time to wait between polls")
+	command := flags.String("exec", config.WatchCommand, "shell `command` to run for each new offer (default prints the offer)")
+	flags.Parse(args)
+
+	if offline {
+		return fmt.Errorf("The watch command needs the network")
+	}
+	if *interval < time.Minute {
+		return fmt.Errorf("The interval must be at least one minute")
+	}
+	slugs, err := filters.locations()
+	if err != nil {
+		return err
+	}
+	locations, err := parseLocations(slugs)
+	if err != nil {
+		return err
+	}
+	state, err := LoadWatchState()
+	if err != nil {
+		return err
+	}
+
+	context := &Context{state: state}
+	if err := filters.apply(context); err != nil {
+		return err
+	}
+	for {
+		pollOffers(context, locations, *command)
+		time.Sleep(*interval)
+	}
+}
+
+// pollOffers fetches the offers for the given locations and runs the command
+// for each new offer that passes the filters of the context. Errors are
+// logged, since the next poll may succeed. A location that fails is skipped,
+// so that it does not hold back the new offers of the other locations.
+func pollOffers(context *Context, locations []Location, command string) {
+	results := make([]LocationOffers, 0, len(locations))
+	for _, location := range locations {
+		// The cache is not used, old offers would be reported as new.
+		offers, err := FetchOffers(gocontext.Background(), location)
+		if err != nil {
+			log.Printf("%s: %s", Locations[location].title, err)
+			continue
+		}
+		results = append(results, LocationOffers{location, offers, &FeedSource{FetchDate: time.Now()}})
+	}
+	if len(results) == 0 {
+		return
+	}
+	if err := context.UpdateLocationOffers(results); err != nil {
+		log.Print(err)
+	}
+
+	for _, offer := range context.FilteredOffers() {
+		if !context.IsNew(offer.ID) {
+			continue
+		}
+		if command == "" {
+			fmt.Printf("%d\t%s\t%s\t%s\n", offer.ID, strings.TrimSpace(offer.Company), strings.TrimSpace(offer.Position), offer.URL)
+			continue
+		}
+		if err := notifyOffer(command, offer, context.OfferLocationNames(offer.ID)); err != nil {
+			log.Printf("Offer %d: %s", offer.ID, err)
+		}
+	}
+}
+
+// notifyOffer runs the shell command for the given offer. The fields of the
+// offer are given to the command as JOBFLUCLI_* environment variables, and
+// the offer is also written to its standard input as JSON.
+func notifyOffer(command string, offer Offer, locations []string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(),
+		"JOBFLUCLI_ID="+strconv.Itoa(offer.ID),
+		"JOBFLUCLI_POSITION="+strings.TrimSpace(offer.Position),
+		"JOBFLUCLI_COMPANY="+strings.TrimSpace(offer.Company),
+		"JOBFLUCLI_DATE="+offer.CreationDate.Format(time.RFC3339),
+		"JOBFLUCLI_TAGS="+strings.Join(offer.Tags, ","),
+		"JOBFLUCLI_URL="+offer.URL,
+		"JOBFLUCLI_LOCATION="+strings.Join(locations, ","),
+	)
+	content, err := json.Marshal(offer)
+	if err != nil {
+		return fmt.Errorf("Cannot encode offer: %s", err)
+	}
+	cmd.Stdin = strings.NewReader(string(content))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Cannot run watch command: %s", err)
+	}
+	return nil
+}